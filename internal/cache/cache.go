@@ -0,0 +1,229 @@
+package cache
+
+import (
+	"crypto"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-uem/openuem-ocsp-responder/internal/signer"
+	"golang.org/x/crypto/ocsp"
+)
+
+// Cache is the last known good store of signed responses. It's used to
+// answer requests while the database is unavailable. If a directory is set,
+// responses are persisted so they survive a restart of the responder
+type Cache struct {
+	dir        string
+	maxEntries int
+	mu         sync.RWMutex
+	entries    map[string]*Entry
+}
+
+type Entry struct {
+	Response *ocsp.Response
+	Raw      []byte
+}
+
+// New creates a cache, loading the responses previously saved in dir. An
+// empty dir keeps the responses in memory only. The cache holds up to
+// maxEntries responses, 0 means no limit
+func New(dir string, maxEntries int) (*Cache, error) {
+	c := Cache{
+		dir:        dir,
+		maxEntries: maxEntries,
+		entries:    map[string]*Entry{},
+	}
+
+	if dir == "" {
+		return &c, nil
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("could not create cache directory %s: %v", dir, err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read cache directory %s: %v", dir, err)
+	}
+
+	now := time.Now()
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".der" {
+			continue
+		}
+
+		path := filepath.Join(dir, f.Name())
+		k := strings.TrimSuffix(f.Name(), ".der")
+		if strings.Count(k, "-") != 2 {
+			// saved by a previous version without the issuer in the key
+			_ = os.Remove(path)
+			continue
+		}
+
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("[WARN]: could not read cached response %s: %v", path, err)
+			continue
+		}
+
+		response, err := signer.ReadResponse(raw)
+		if err != nil || !now.Before(response.NextUpdate) {
+			// corrupted or expired responses are useless
+			_ = os.Remove(path)
+			continue
+		}
+
+		c.entries[k] = &Entry{Response: response, Raw: raw}
+	}
+
+	// the limit may have been lowered since the responses were saved
+	for c.maxEntries > 0 && len(c.entries) > c.maxEntries {
+		c.evict(now)
+	}
+
+	return &c, nil
+}
+
+// Get returns the cached response for the certificate, identified by the
+// CertID hash algorithm, the issuer key hash and its serial, if it's still
+// valid
+func (c *Cache) Get(hash crypto.Hash, issuerKeyHash []byte, serial *big.Int) (*Entry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[key(hash, issuerKeyHash, serial)]
+	if !ok || !time.Now().Before(entry.Response.NextUpdate) {
+		return nil, false
+	}
+	return entry, true
+}
+
+// Put saves a signed response. It's only written to disk if it's newer than
+// the one already cached. If the cache is full an entry is evicted first
+func (c *Cache) Put(hash crypto.Hash, issuerKeyHash []byte, raw []byte) error {
+	response, err := signer.ReadResponse(raw)
+	if err != nil {
+		return err
+	}
+
+	k := key(hash, issuerKeyHash, response.SerialNumber)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	old, ok := c.entries[k]
+	if ok && !response.ThisUpdate.After(old.Response.ThisUpdate) && old.Response.Status == response.Status {
+		return nil
+	}
+	if !ok && c.full() {
+		c.evict(time.Now())
+	}
+	c.entries[k] = &Entry{Response: response, Raw: raw}
+
+	if c.dir == "" {
+		return nil
+	}
+
+	path := filepath.Join(c.dir, k+".der")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func key(hash crypto.Hash, issuerKeyHash []byte, serial *big.Int) string {
+	name := strings.ToLower(strings.ReplaceAll(hash.String(), "-", ""))
+	return fmt.Sprintf("%s-%x-%x", name, issuerKeyHash, serial)
+}
+
+func (c *Cache) full() bool {
+	return c.maxEntries > 0 && len(c.entries) >= c.maxEntries
+}
+
+// evict makes room for a new entry, the expired entries are removed and, if
+// there's none, an arbitrary one. It must be called with the lock held
+func (c *Cache) evict(now time.Time) {
+	if c.prune(now) > 0 {
+		return
+	}
+	for k := range c.entries {
+		c.remove(k)
+		return
+	}
+}
+
+// prune removes the expired entries and returns how many were removed. It
+// must be called with the lock held
+func (c *Cache) prune(now time.Time) int {
+	removed := 0
+	for k, entry := range c.entries {
+		if !now.Before(entry.Response.NextUpdate) {
+			c.remove(k)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (c *Cache) remove(k string) {
+	delete(c.entries, k)
+	if c.dir != "" {
+		if err := os.Remove(filepath.Join(c.dir, k+".der")); err != nil && !os.IsNotExist(err) {
+			log.Printf("[WARN]: could not remove cached response %s: %v", k, err)
+		}
+	}
+}
+
+// Prune removes the responses past their NextUpdate, both from memory and
+// from disk
+func (c *Cache) Prune() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.prune(time.Now())
+}
+
+// Stats describes the content of the cache
+type Stats struct {
+	Entries    int    `json:"entries"`
+	Valid      int    `json:"valid"`
+	MaxEntries int    `json:"max_entries"`
+	Dir        string `json:"dir"`
+}
+
+func (c *Cache) Stats() Stats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	stats := Stats{Entries: len(c.entries), MaxEntries: c.maxEntries, Dir: c.dir}
+	now := time.Now()
+	for _, entry := range c.entries {
+		if now.Before(entry.Response.NextUpdate) {
+			stats.Valid++
+		}
+	}
+	return stats
+}
+
+// Flush removes every cached response, both from memory and from disk
+func (c *Cache) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k := range c.entries {
+		if c.dir != "" {
+			if err := os.Remove(filepath.Join(c.dir, k+".der")); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		delete(c.entries, k)
+	}
+	return nil
+}
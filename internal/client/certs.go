@@ -0,0 +1,80 @@
+package client
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// ReadCertificates returns every certificate in a PEM file, or the one in a
+// DER file
+func ReadCertificates(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", path, err)
+	}
+	return parseCertificates(data)
+}
+
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	certs := []*x509.Certificate{}
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		cert, err := x509.ParseCertificate(data)
+		if err != nil {
+			return nil, errors.New("no certificates found")
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// FetchIssuer downloads the issuer of cert from its CA Issuers URL
+func FetchIssuer(cert *x509.Certificate, timeout time.Duration) (*x509.Certificate, error) {
+	if len(cert.IssuingCertificateURL) == 0 {
+		return nil, errors.New("the certificate doesn't include the issuer URL, set the issuer with --issuer")
+	}
+
+	httpClient := http.Client{Timeout: timeout}
+	resp, err := httpClient.Get(cert.IssuingCertificateURL[0])
+	if err != nil {
+		return nil, fmt.Errorf("could not download the issuer: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not download the issuer from %s: %s", cert.IssuingCertificateURL[0], resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("could not download the issuer: %v", err)
+	}
+
+	certs, err := parseCertificates(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the issuer: %v", err)
+	}
+	return certs[0], nil
+}
@@ -0,0 +1,284 @@
+package client
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/open-uem/openuem-ocsp-responder/internal/signer"
+	"golang.org/x/crypto/ocsp"
+)
+
+// Options holds the settings used to query a responder
+type Options struct {
+	// URL of the responder, if empty the OCSP URL of the certificate is used
+	URL string
+	// Hash is the CertID hash algorithm
+	Hash crypto.Hash
+	// Nonce adds a random nonce to the request
+	Nonce bool
+	// Method is GET or POST
+	Method string
+	// Timeout of the HTTP request
+	Timeout time.Duration
+	// ClockSkew is the tolerance applied to the validity of the response
+	ClockSkew time.Duration
+	// IssuerFromAIA is set when the issuer was downloaded from the CA
+	// Issuers URL of the certificate, so it's not a trusted issuer
+	IssuerFromAIA bool
+}
+
+// Result is the verdict of a check with the details of the response
+type Result struct {
+	URL                string     `json:"url"`
+	Method             string     `json:"method"`
+	HTTPStatus         int        `json:"http_status"`
+	Serial             string     `json:"serial"`
+	Status             string     `json:"status"`
+	RevokedAt          *time.Time `json:"revoked_at,omitempty"`
+	RevocationReason   *int       `json:"revocation_reason,omitempty"`
+	ProducedAt         *time.Time `json:"produced_at,omitempty"`
+	ThisUpdate         *time.Time `json:"this_update,omitempty"`
+	NextUpdate         *time.Time `json:"next_update,omitempty"`
+	CertIDHash         string     `json:"cert_id_hash"`
+	IssuerFromAIA      bool       `json:"issuer_from_aia"`
+	ResponderName      string     `json:"responder_name,omitempty"`
+	ResponderKeyHash   string     `json:"responder_key_hash,omitempty"`
+	SignatureAlgorithm string     `json:"signature_algorithm,omitempty"`
+	Signer             string     `json:"signer,omitempty"`
+	SignerSerial       string     `json:"signer_serial,omitempty"`
+	SignerNotAfter     *time.Time `json:"signer_not_after,omitempty"`
+	Nonce              string     `json:"nonce,omitempty"`
+	NonceReturned      bool       `json:"nonce_returned"`
+	Valid              bool       `json:"valid"`
+	Errors             []string   `json:"errors,omitempty"`
+	Warnings           []string   `json:"warnings,omitempty"`
+}
+
+type ocspRequest struct {
+	TBSRequest tbsRequest
+}
+
+type tbsRequest struct {
+	Version           int `asn1:"explicit,tag:0,default:0,optional"`
+	RequestList       []singleRequest
+	RequestExtensions []pkix.Extension `asn1:"explicit,tag:2,optional"`
+}
+
+type singleRequest struct {
+	Cert certID
+}
+
+type certID struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	NameHash      []byte
+	IssuerKeyHash []byte
+	SerialNumber  *big.Int
+}
+
+// Check asks the responder for the status of cert and verifies the response.
+// The returned error is only set if the responder couldn't be queried, any
+// problem with the response is reported in the result
+func Check(cert, issuer *x509.Certificate, opts Options) (*Result, error) {
+	result := &Result{
+		URL:           opts.URL,
+		Method:        strings.ToUpper(opts.Method),
+		Serial:        fmt.Sprintf("%X", cert.SerialNumber),
+		CertIDHash:    opts.Hash.String(),
+		IssuerFromAIA: opts.IssuerFromAIA,
+	}
+
+	if result.URL == "" {
+		if len(cert.OCSPServer) == 0 {
+			return nil, errors.New("the certificate doesn't include an OCSP URL, set it with --url")
+		}
+		result.URL = cert.OCSPServer[0]
+	}
+	if result.Method == "" {
+		result.Method = http.MethodPost
+	}
+	if result.Method != http.MethodGet && result.Method != http.MethodPost {
+		return nil, fmt.Errorf("unsupported method %s, use GET or POST", opts.Method)
+	}
+
+	// the CertID is computed from the issuer, if it didn't issue the
+	// certificate the response can't be about it
+	if err := cert.CheckSignatureFrom(issuer); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("the certificate is not signed by the issuer: %v", err))
+	}
+	if opts.IssuerFromAIA {
+		result.Warnings = append(result.Warnings, "the issuer was downloaded from the CA Issuers URL and is not trusted, set it with --issuer")
+	}
+
+	var nonce []byte
+	if opts.Nonce {
+		nonce = make([]byte, 32)
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		result.Nonce = hex.EncodeToString(nonce)
+	}
+
+	request, err := createRequest(cert, issuer, opts.Hash, nonce)
+	if err != nil {
+		return nil, fmt.Errorf("could not create the request: %v", err)
+	}
+
+	der, status, err := send(result.URL, result.Method, request, opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	result.HTTPStatus = status
+
+	resp, responseNonce, err := signer.ParseResponse(der, cert, issuer)
+	if err != nil {
+		var responseError ocsp.ResponseError
+		if errors.As(err, &responseError) {
+			result.Status = responseError.Status.String()
+		}
+		result.Errors = append(result.Errors, err.Error())
+		return result, nil
+	}
+
+	result.Status = statusName(resp.Status)
+	result.ProducedAt = &resp.ProducedAt
+	result.ThisUpdate = &resp.ThisUpdate
+	if !resp.NextUpdate.IsZero() {
+		result.NextUpdate = &resp.NextUpdate
+	}
+	if resp.Status == ocsp.Revoked {
+		result.RevokedAt = &resp.RevokedAt
+		result.RevocationReason = &resp.RevocationReason
+	}
+	if resp.RawResponderName != nil {
+		var name pkix.RDNSequence
+		if _, err := asn1.Unmarshal(resp.RawResponderName, &name); err == nil {
+			result.ResponderName = name.String()
+		}
+	}
+	if resp.ResponderKeyHash != nil {
+		result.ResponderKeyHash = fmt.Sprintf("%X", resp.ResponderKeyHash)
+	}
+	result.SignatureAlgorithm = resp.SignatureAlgorithm.String()
+
+	responder := issuer
+	if resp.Certificate != nil {
+		responder = resp.Certificate
+	}
+	result.Signer = responder.Subject.String()
+	result.SignerSerial = fmt.Sprintf("%X", responder.SerialNumber)
+	result.SignerNotAfter = &responder.NotAfter
+
+	now := time.Now()
+	if now.After(responder.NotAfter) || now.Before(responder.NotBefore) {
+		result.Errors = append(result.Errors, "the responder certificate is not valid at the current time")
+	}
+	if resp.IssuerHash != opts.Hash {
+		result.Errors = append(result.Errors, fmt.Sprintf("the response uses the %s CertID hash instead of %s", resp.IssuerHash, opts.Hash))
+	}
+	if resp.ThisUpdate.After(now.Add(opts.ClockSkew)) {
+		result.Errors = append(result.Errors, "the response is not valid yet")
+	}
+	if resp.NextUpdate.IsZero() {
+		result.Warnings = append(result.Warnings, "the response has no next update time")
+	} else if now.Add(-opts.ClockSkew).After(resp.NextUpdate) {
+		result.Errors = append(result.Errors, "the response has expired")
+	}
+
+	if responseNonce != nil {
+		result.NonceReturned = true
+		if !bytes.Equal(responseNonce, nonce) {
+			result.Errors = append(result.Errors, "the nonce of the response doesn't match the request")
+		}
+	} else if nonce != nil {
+		result.Warnings = append(result.Warnings, "the responder didn't return the nonce, the response may be cached")
+	}
+
+	result.Valid = len(result.Errors) == 0
+	return result, nil
+}
+
+// createRequest returns a DER encoded request for cert, with a nonce
+// extension if nonce is set
+func createRequest(cert, issuer *x509.Certificate, hash crypto.Hash, nonce []byte) ([]byte, error) {
+	// ocsp.CreateRequest computes the CertID, it's decoded again to add the
+	// nonce extension which isn't supported by x/crypto
+	der, err := ocsp.CreateRequest(cert, issuer, &ocsp.RequestOptions{Hash: hash})
+	if err != nil {
+		return nil, err
+	}
+	if nonce == nil {
+		return der, nil
+	}
+
+	var req ocspRequest
+	if _, err := asn1.Unmarshal(der, &req); err != nil {
+		return nil, err
+	}
+
+	value, err := asn1.Marshal(nonce)
+	if err != nil {
+		return nil, err
+	}
+	req.TBSRequest.RequestExtensions = []pkix.Extension{{Id: signer.OIDNonce, Value: value}}
+
+	return asn1.Marshal(req)
+}
+
+// send posts the request or gets it using the base64 encoded request as the
+// URL path, see RFC 6960 Appendix A.1
+func send(responderURL, method string, request []byte, timeout time.Duration) ([]byte, int, error) {
+	var httpReq *http.Request
+	var err error
+
+	if method == http.MethodGet {
+		encoded := url.PathEscape(base64.StdEncoding.EncodeToString(request))
+		httpReq, err = http.NewRequest(http.MethodGet, strings.TrimSuffix(responderURL, "/")+"/"+encoded, nil)
+	} else {
+		httpReq, err = http.NewRequest(http.MethodPost, responderURL, bytes.NewReader(request))
+		if err == nil {
+			httpReq.Header.Set("Content-Type", "application/ocsp-request")
+		}
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not create the HTTP request: %v", err)
+	}
+	httpReq.Header.Set("Accept", "application/ocsp-response")
+
+	httpClient := http.Client{Timeout: timeout}
+	httpResp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not query the responder: %v", err)
+	}
+	defer httpResp.Body.Close()
+
+	der, err := io.ReadAll(io.LimitReader(httpResp.Body, 1<<20))
+	if err != nil {
+		return nil, httpResp.StatusCode, fmt.Errorf("could not read the response: %v", err)
+	}
+	return der, httpResp.StatusCode, nil
+}
+
+func statusName(status int) string {
+	switch status {
+	case ocsp.Good:
+		return "good"
+	case ocsp.Revoked:
+		return "revoked"
+	default:
+		return "unknown"
+	}
+}
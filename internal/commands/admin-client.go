@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/open-uem/openuem-ocsp-responder/internal/common"
+	"github.com/open-uem/openuem-ocsp-responder/internal/server"
+	"github.com/urfave/cli/v2"
+)
+
+// adminAddress returns the address of the admin API, set with the admin flag
+// or resolved as the responder does: the first admin listen address from the
+// environment or the configuration file. It's empty if the admin API isn't
+// enabled
+func adminAddress(cCtx *cli.Context) (string, error) {
+	if address := cCtx.String("admin"); address != "" {
+		return address, nil
+	}
+
+	s, err := common.NewSettings(cCtx, cCtx.String("config"))
+	if err != nil {
+		return "", err
+	}
+	addresses := s.Strings("admin-listen")
+	if err := s.Err(); err != nil {
+		return "", err
+	}
+
+	if len(addresses) == 0 {
+		return "", nil
+	}
+	return addresses[0], nil
+}
+
+// adminRequest sends a request to the admin API listening on address, which
+// may be a Unix domain socket. TLS is used if the admin CA flag is set
+func adminRequest(cCtx *cli.Context, address, method, path string) (*http.Response, error) {
+	network, addr := server.ParseAddress(address)
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+
+	scheme := "http"
+	if cCtx.String("admin-ca") != "" {
+		tlsConfig, err := adminClientTLSConfig(cCtx)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+		scheme = "https"
+	}
+
+	host := addr
+	if network == "unix" {
+		host = "localhost"
+	}
+
+	req, err := http.NewRequest(method, scheme+"://"+host+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := http.Client{Transport: transport, Timeout: cCtx.Duration("timeout")}
+	return client.Do(req)
+}
+
+func adminClientTLSConfig(cCtx *cli.Context) (*tls.Config, error) {
+	pem, err := os.ReadFile(cCtx.String("admin-ca"))
+	if err != nil {
+		return nil, fmt.Errorf("could not read the admin CA: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", cCtx.String("admin-ca"))
+	}
+
+	tlsConfig := &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+
+	if cCtx.String("admin-client-cert") != "" || cCtx.String("admin-client-key") != "" {
+		cert, err := tls.LoadX509KeyPair(cCtx.String("admin-client-cert"), cCtx.String("admin-client-key"))
+		if err != nil {
+			return nil, fmt.Errorf("could not read the admin client certificate and key: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
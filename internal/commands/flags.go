@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"github.com/open-uem/openuem-ocsp-responder/internal/common"
+	"github.com/urfave/cli/v2"
+)
+
+// CertificateFlags are the flags used to read the CA certificate and the
+// OCSP signer certificate and private key, from files or inline in PEM format
+func CertificateFlags() []cli.Flag {
+	return common.Flags(
+		"base-dir", "cacert", "cacert-pem", "cert", "cert-pem", "key", "key-pem", "key-passphrase", "key-passphrase-file", "key-passphrase-credential",
+		"pkcs11-module", "pkcs11-slot", "pkcs11-token-label", "pkcs11-key-label", "pkcs11-pin", "pkcs11-pin-file",
+		"responder-id", "signature-algorithm",
+	)
+}
+
+// ConfigFlag is the path of the configuration file, whose settings are
+// overridden by the environment and the flags
+func ConfigFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:    "config",
+		Usage:   "the path to a configuration file in INI format, environment variables and flags take precedence over its settings",
+		EnvVars: []string{"OCSP_CONFIG"},
+	}
+}
+
+// PIDFileFlag is the path of the PID file shared by the lifecycle commands
+func PIDFileFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:    "pidfile",
+		Usage:   "the path to the PID file of the running responder",
+		EnvVars: []string{"OCSP_PIDFILE"},
+		Value:   "PIDFILE",
+	}
+}
+
+// AdminClientFlags are the flags used to query the admin API of the running
+// responder. Its address is read from the configuration file if not set
+func AdminClientFlags() []cli.Flag {
+	return []cli.Flag{
+		ConfigFlag(),
+		&cli.StringFlag{
+			Name:    "admin",
+			Usage:   "the admin API address of the running responder, e.g. unix:/run/openuem/ocsp-admin.sock or 127.0.0.1:8001, by default the first admin listen address of the configuration",
+			EnvVars: []string{"OCSP_ADMIN_ADDRESS"},
+		},
+		&cli.StringFlag{
+			Name:  "admin-ca",
+			Usage: "the path to the CA certificate of the admin API server, enables TLS",
+		},
+		&cli.StringFlag{
+			Name:  "admin-client-cert",
+			Usage: "the path to the client certificate used with mutual TLS",
+		},
+		&cli.StringFlag{
+			Name:  "admin-client-key",
+			Usage: "the path to the client private key used with mutual TLS",
+		},
+	}
+}
+
+// DatabaseFlags are the flags used to connect with the database
+func DatabaseFlags() []cli.Flag {
+	return common.Flags(
+		"dburl", "dburl-replica", "db-max-open-conns", "db-max-idle-conns", "db-conn-max-lifetime", "db-query-timeout",
+		"db-retry-initial", "db-retry-max", "db-retry-jitter", "db-retry-max-attempts", "db-probe-interval",
+	)
+}
@@ -0,0 +1,194 @@
+package commands
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/open-uem/openuem-ocsp-responder/internal/client"
+	"github.com/open-uem/openuem-ocsp-responder/internal/common"
+	"github.com/urfave/cli/v2"
+)
+
+// Exit codes of the check command, 0 means the certificate is good
+const (
+	checkRevoked = 1
+	checkUnknown = 2
+	checkFailed  = 3
+)
+
+func CheckCertificate() *cli.Command {
+	return &cli.Command{
+		Name:      "check",
+		Usage:     "Ask an OCSP responder for the status of a certificate and verify the response",
+		ArgsUsage: " ",
+		Action:    checkCertificate,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "cert",
+				Usage:    "the path to the certificate to check, in PEM or DER format. If the file contains a chain the second certificate is used as the issuer",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "issuer",
+				Usage: "the path to the issuer certificate, by default it's taken from the certificate file or downloaded from its CA Issuers URL",
+			},
+			&cli.StringFlag{
+				Name:  "url",
+				Usage: "the URL of the OCSP responder, by default the OCSP URL of the certificate",
+			},
+			&cli.StringFlag{
+				Name:  "hash",
+				Usage: "the CertID hash algorithm (sha1, sha256, sha384, sha512)",
+				Value: "sha1",
+			},
+			&cli.BoolFlag{
+				Name:  "nonce",
+				Usage: "add a nonce to the request, the OpenUEM responder doesn't return it as its responses may be cached",
+			},
+			&cli.StringFlag{
+				Name:  "method",
+				Usage: "the HTTP method used to send the request, GET or POST",
+				Value: "POST",
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "the timeout of the HTTP requests",
+				Value: 10 * time.Second,
+			},
+			&cli.DurationFlag{
+				Name:  "clock-skew",
+				Usage: "the tolerance applied when checking the validity period of the response",
+				Value: 5 * time.Minute,
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "print the result in JSON format",
+			},
+		},
+	}
+}
+
+func checkCertificate(cCtx *cli.Context) error {
+	hash, err := common.ParseHashAlgorithm(cCtx.String("hash"))
+	if err != nil {
+		return cli.Exit(err, checkFailed)
+	}
+
+	certs, err := client.ReadCertificates(cCtx.String("cert"))
+	if err != nil {
+		return cli.Exit(err, checkFailed)
+	}
+	cert := certs[0]
+
+	issuer, fromAIA, err := readIssuer(cCtx, certs)
+	if err != nil {
+		return cli.Exit(err, checkFailed)
+	}
+
+	result, err := client.Check(cert, issuer, client.Options{
+		URL:           cCtx.String("url"),
+		Hash:          hash,
+		Nonce:         cCtx.Bool("nonce"),
+		Method:        cCtx.String("method"),
+		Timeout:       cCtx.Duration("timeout"),
+		ClockSkew:     cCtx.Duration("clock-skew"),
+		IssuerFromAIA: fromAIA,
+	})
+	if err != nil {
+		return cli.Exit(err, checkFailed)
+	}
+
+	if cCtx.Bool("json") {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return cli.Exit(err, checkFailed)
+		}
+		fmt.Println(string(data))
+	} else {
+		printCheckResult(result)
+	}
+
+	switch {
+	case !result.Valid:
+		return cli.Exit("", checkFailed)
+	case result.Status == "revoked":
+		return cli.Exit("", checkRevoked)
+	case result.Status == "unknown":
+		return cli.Exit("", checkUnknown)
+	default:
+		return nil
+	}
+}
+
+// readIssuer returns the issuer set with the issuer flag, the second
+// certificate of the chain or the one published in the CA Issuers URL, and
+// if it was downloaded from that URL
+func readIssuer(cCtx *cli.Context, certs []*x509.Certificate) (*x509.Certificate, bool, error) {
+	if cCtx.String("issuer") != "" {
+		issuers, err := client.ReadCertificates(cCtx.String("issuer"))
+		if err != nil {
+			return nil, false, err
+		}
+		return issuers[0], false, nil
+	}
+
+	if len(certs) > 1 {
+		return certs[1], false, nil
+	}
+
+	issuer, err := client.FetchIssuer(certs[0], cCtx.Duration("timeout"))
+	return issuer, err == nil, err
+}
+
+func printCheckResult(r *client.Result) {
+	fmt.Printf("Responder URL:        %s (%s, HTTP %d)\n", r.URL, r.Method, r.HTTPStatus)
+	fmt.Printf("Certificate serial:   %s\n", r.Serial)
+	fmt.Printf("CertID hash:          %s\n", r.CertIDHash)
+	fmt.Printf("Status:               %s\n", r.Status)
+	if r.RevokedAt != nil {
+		fmt.Printf("Revoked at:           %s\n", r.RevokedAt.Format(time.RFC3339))
+	}
+	if r.RevocationReason != nil {
+		fmt.Printf("Revocation reason:    %d\n", *r.RevocationReason)
+	}
+	if r.ProducedAt != nil {
+		fmt.Printf("Produced at:          %s\n", r.ProducedAt.Format(time.RFC3339))
+	}
+	if r.ThisUpdate != nil {
+		fmt.Printf("This update:          %s\n", r.ThisUpdate.Format(time.RFC3339))
+	}
+	if r.NextUpdate != nil {
+		fmt.Printf("Next update:          %s\n", r.NextUpdate.Format(time.RFC3339))
+	}
+	if r.ResponderName != "" {
+		fmt.Printf("Responder ID (name):  %s\n", r.ResponderName)
+	}
+	if r.ResponderKeyHash != "" {
+		fmt.Printf("Responder ID (key):   %s\n", r.ResponderKeyHash)
+	}
+	if r.Signer != "" {
+		fmt.Printf("Signed by:            %s (serial %s, expires %s)\n", r.Signer, r.SignerSerial, r.SignerNotAfter.Format(time.RFC3339))
+		fmt.Printf("Signature algorithm:  %s\n", r.SignatureAlgorithm)
+	}
+	if r.Nonce != "" {
+		fmt.Printf("Nonce returned:       %t\n", r.NonceReturned)
+	}
+	for _, warning := range r.Warnings {
+		fmt.Printf("Warning:              %s\n", warning)
+	}
+	for _, e := range r.Errors {
+		fmt.Printf("Error:                %s\n", e)
+	}
+
+	switch {
+	case r.Valid && r.IssuerFromAIA:
+		fmt.Printf("Verdict:              %s, the response is valid for an untrusted issuer\n", strings.ToUpper(r.Status))
+	case r.Valid:
+		fmt.Printf("Verdict:              %s, the response is valid\n", strings.ToUpper(r.Status))
+	default:
+		fmt.Println("Verdict:              FAILED, the response could not be verified")
+	}
+}
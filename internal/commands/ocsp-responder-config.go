@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/open-uem/openuem-ocsp-responder/internal/common"
+	"github.com/urfave/cli/v2"
+)
+
+func ConfigOCSPResponder() *cli.Command {
+	return &cli.Command{
+		Name:  "config",
+		Usage: "Validate or show the configuration of the OCSP Responder",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "validate",
+				Usage:  "Check the configuration, the certificates, the signing key, the database urls and the listen addresses without starting the responder",
+				Action: validateConfig,
+				Flags:  configFlags(),
+			},
+			{
+				Name:   "show",
+				Usage:  "Print the effective configuration, and where each setting comes from, with secrets redacted",
+				Action: showConfig,
+				Flags:  configFlags(),
+			},
+		},
+	}
+}
+
+// configFlags are the flags of the start command, so the configuration is
+// loaded as start would, plus the flags of the config subcommands
+func configFlags() []cli.Flag {
+	return append(OCSPResponderFlags(),
+		&cli.BoolFlag{
+			Name:  "service",
+			Usage: "load the configuration as the service does, from its configuration file and the environment, the other flags are ignored",
+		},
+		&cli.BoolFlag{
+			Name:  "json",
+			Usage: "print the result in JSON format",
+		},
+	)
+}
+
+func loadSettings(cCtx *cli.Context) (*common.Settings, string, error) {
+	if cCtx.Bool("service") {
+		return common.NewServiceSettings()
+	}
+
+	configFile := cCtx.String("config")
+	s, err := common.NewSettings(cCtx, configFile)
+	return s, configFile, err
+}
+
+func validateConfig(cCtx *cli.Context) error {
+	s, configFile, err := loadSettings(cCtx)
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+
+	// The problems are reported by the checks, not logged
+	log.SetOutput(io.Discard)
+	checks := common.ValidateConfig(s)
+	log.SetOutput(os.Stderr)
+
+	if cCtx.Bool("json") {
+		data, err := json.MarshalIndent(map[string]any{"config_file": configFile, "checks": checks}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		printConfigFile(configFile)
+		for _, check := range checks {
+			fmt.Printf("[%-7s] %s: %s\n", strings.ToUpper(check.Status), check.Name, check.Message)
+		}
+	}
+
+	if common.Failed(checks) {
+		return cli.Exit("the configuration is not valid", 1)
+	}
+	if !cCtx.Bool("json") {
+		fmt.Println("The configuration is valid")
+	}
+	return nil
+}
+
+// showConfig prints the configuration in INI format, grouped by section, with
+// the source of each setting as a comment. Secrets and inline PEM data are
+// replaced by placeholders, so the output is meant to be read, it can't be
+// used as a configuration file as is
+func showConfig(cCtx *cli.Context) error {
+	s, configFile, err := loadSettings(cCtx)
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+	values := s.Effective()
+
+	if cCtx.Bool("json") {
+		data, err := json.MarshalIndent(map[string]any{"config_file": configFile, "settings": values}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Print("; ")
+	printConfigFile(configFile)
+
+	// The settings of a section aren't contiguous in the table, they're
+	// grouped so every section is printed once
+	sections := []string{}
+	bySection := map[string][]common.SettingValue{}
+	for _, v := range values {
+		if _, ok := bySection[v.Section]; !ok {
+			sections = append(sections, v.Section)
+		}
+		bySection[v.Section] = append(bySection[v.Section], v)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	for _, section := range sections {
+		fmt.Fprintf(tw, "\n[%s]\n", section)
+		for _, v := range bySection[section] {
+			comment := v.Source
+			if v.Error != "" {
+				comment = v.Error
+			}
+			fmt.Fprintf(tw, "%s\t= %s\t; %s\n", v.Key, formatSetting(v.Value), comment)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatSetting(value any) string {
+	if values, ok := value.([]string); ok {
+		return strings.Join(values, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+func printConfigFile(configFile string) {
+	if configFile == "" {
+		fmt.Println("Configuration file: none")
+		return
+	}
+	fmt.Printf("Configuration file: %s\n", configFile)
+}
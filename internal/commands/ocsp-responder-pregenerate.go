@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+	"github.com/open-uem/openuem-ocsp-responder/internal/common"
+	"github.com/open-uem/openuem-ocsp-responder/internal/models"
+	"github.com/urfave/cli/v2"
+)
+
+func PregenerateOCSPResponses() *cli.Command {
+	flags := append([]cli.Flag{ConfigFlag()}, CertificateFlags()...)
+	flags = append(flags, DatabaseFlags()...)
+	flags = append(flags,
+		&cli.StringFlag{
+			Name:     "output",
+			Usage:    "the directory where the signed responses are written, laid out by the RFC 5019 GET path and by serial",
+			EnvVars:  []string{"OCSP_PREGENERATE_DIR"},
+			Required: true,
+		},
+		&cli.StringSliceFlag{
+			Name:    "hash",
+			Usage:   "the CertID hash algorithm of the requests the responses are generated for (sha1, sha256, sha384, sha512). Can be repeated",
+			EnvVars: []string{"OCSP_PREGENERATE_HASHES"},
+			Value:   cli.NewStringSlice("sha1"),
+		},
+		&cli.DurationFlag{
+			Name:    "interval",
+			Usage:   "regenerate the responses periodically with this interval, which must be shorter than the response validity (1 day). If zero the responses are generated once",
+			EnvVars: []string{"OCSP_PREGENERATE_INTERVAL"},
+		},
+	)
+
+	return &cli.Command{
+		Name:   "pregenerate",
+		Usage:  "Sign responses for every known certificate so they can be served from a static file server",
+		Action: pregenerateOCSPResponses,
+		Flags:  flags,
+	}
+}
+
+func pregenerateOCSPResponses(cCtx *cli.Context) error {
+	hashes, err := common.ParseHashAlgorithms(cCtx.StringSlice("hash"))
+	if err != nil {
+		return err
+	}
+
+	interval := cCtx.Duration("interval")
+	if interval >= 24*time.Hour {
+		return fmt.Errorf("the interval must be shorter than the response validity (24h)")
+	}
+
+	worker := common.NewWorker("")
+	if err := worker.GenerateOCSPResponderConfigFromCLI(cCtx); err != nil {
+		return fmt.Errorf("could not generate config for OCSP responder: %v", err)
+	}
+
+	worker.Model, err = models.New(worker.DBUrl, worker.DBReplicaUrls, worker.DBOptions)
+	if err != nil {
+		return fmt.Errorf("could not connect with database: %v", err)
+	}
+	defer worker.Model.Close()
+
+	output := cCtx.String("output")
+	if err := worker.PregenerateResponses(output, hashes); err != nil {
+		return err
+	}
+
+	if interval == 0 {
+		return nil
+	}
+
+	worker.TaskScheduler, err = gocron.NewScheduler()
+	if err != nil {
+		return fmt.Errorf("could not create task scheduler, reason: %v", err)
+	}
+
+	if _, err := worker.TaskScheduler.NewJob(
+		gocron.DurationJob(interval),
+		gocron.NewTask(
+			func() {
+				if err := worker.PregenerateResponses(output, hashes); err != nil {
+					log.Printf("[ERROR]: could not pregenerate responses, reason: %v", err)
+				}
+			},
+		),
+	); err != nil {
+		return fmt.Errorf("could not schedule the pregenerate job: %v", err)
+	}
+	worker.TaskScheduler.Start()
+	log.Printf("[INFO]: responses will be pregenerated every %s", interval)
+
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+	<-done
+
+	if err := worker.TaskScheduler.Shutdown(); err != nil {
+		log.Printf("[ERROR]: could not stop the task scheduler, reason: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/open-uem/openuem-ocsp-responder/internal/pidfile"
+	"github.com/urfave/cli/v2"
+)
+
+func ReloadOCSPResponder() *cli.Command {
+	return &cli.Command{
+		Name:   "reload",
+		Usage:  "Reload the configuration of the running OCSP Responder",
+		Action: reloadOCSPResponder,
+		Flags: append(append([]cli.Flag{PIDFileFlag()}, AdminClientFlags()...),
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "the timeout of the admin API request",
+				Value: 30 * time.Second,
+			},
+		),
+	}
+}
+
+// reloadOCSPResponder asks the admin API to reload the configuration, which
+// reports whether it could be applied, or sends SIGHUP to the responder in
+// the PID file, which logs the result
+func reloadOCSPResponder(cCtx *cli.Context) error {
+	address, err := adminAddress(cCtx)
+	if err != nil {
+		return fmt.Errorf("could not read the admin API address: %v", err)
+	}
+
+	if address != "" {
+		resp, err := adminRequest(cCtx, address, http.MethodPost, "/reload")
+		if err != nil {
+			return fmt.Errorf("could not query the admin API: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			body := map[string]string{}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err == nil && body["error"] != "" {
+				return errors.New(body["error"])
+			}
+			return fmt.Errorf("the admin API answered %s", resp.Status)
+		}
+
+		log.Printf("✅ Done! The configuration of your OCSP responder has been reloaded\n\n")
+		return nil
+	}
+
+	if runtime.GOOS == "windows" {
+		return errors.New("signals are not supported on Windows, enable the admin API or set --admin to reload the configuration")
+	}
+
+	path := cCtx.String("pidfile")
+	pid, err := pidfile.Read(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("could not find the PID file %s, the OCSP responder is not running", path)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Only the running instance holds the lock, so the pid is not signalled
+	// if it has been reused by another process
+	if !pidfile.Locked(path) {
+		return fmt.Errorf("the OCSP responder is not running, the PID file %s is stale", path)
+	}
+
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return fmt.Errorf("could not find process associated with OCSP Responder")
+	}
+
+	if err := p.Signal(syscall.SIGHUP); err != nil {
+		return fmt.Errorf("could not signal the process associated with OCSP Responder, reason: %s", err.Error())
+	}
+
+	log.Printf("✅ Done! Your OCSP responder (pid %d) is reloading its configuration, check its log for the result\n\n", pid)
+	return nil
+}
@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/open-uem/openuem-ocsp-responder/internal/common"
+	"github.com/open-uem/openuem-ocsp-responder/internal/pidfile"
+	"github.com/urfave/cli/v2"
+)
+
+// Exit codes of the status command, they follow the LSB init script
+// conventions. 0 means the responder is running, ready and every database is
+// reachable. LSB reserves 150 to 199 for the application, a degraded
+// responder is running but not serving fresh responses
+const (
+	statusDead     = 1
+	statusStopped  = 3
+	statusUnknown  = 4
+	statusDegraded = 150
+)
+
+func StatusOCSPResponder() *cli.Command {
+	return &cli.Command{
+		Name:   "status",
+		Usage:  "Show the status of the running OCSP Responder",
+		Action: statusOCSPResponder,
+		Flags: append(append([]cli.Flag{PIDFileFlag()}, AdminClientFlags()...),
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "the timeout of the admin API request",
+				Value: 5 * time.Second,
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "print the status in JSON format",
+			},
+		),
+	}
+}
+
+func statusOCSPResponder(cCtx *cli.Context) error {
+	address, err := adminAddress(cCtx)
+	if err != nil {
+		fmt.Printf("Could not read the admin API address: %v\n", err)
+		return cli.Exit("", statusUnknown)
+	}
+
+	if address != "" {
+		status, err := queryStatus(cCtx, address)
+		if err == nil {
+			return printStatus(cCtx, status)
+		}
+
+		if pid, running := readPID(cCtx.String("pidfile")); running {
+			fmt.Printf("OCSP responder is running (pid %d) but the admin API can't be queried: %v\n", pid, err)
+			return cli.Exit("", statusUnknown)
+		}
+		fmt.Printf("OCSP responder is not running: %v\n", err)
+		return cli.Exit("", statusStopped)
+	}
+
+	pid, running := readPID(cCtx.String("pidfile"))
+	switch {
+	case running:
+		fmt.Printf("OCSP responder is running (pid %d), enable the admin API for details\n", pid)
+		return nil
+	case pid > 0:
+		fmt.Printf("OCSP responder is not running but the PID file %s exists (pid %d)\n", cCtx.String("pidfile"), pid)
+		return cli.Exit("", statusDead)
+	default:
+		fmt.Println("OCSP responder is not running")
+		return cli.Exit("", statusStopped)
+	}
+}
+
+// readPID returns the pid in the PID file, or 0 if there's none, and whether
+// the process is still running, which holds the lock of the file
+func readPID(path string) (int, bool) {
+	pid, err := pidfile.Read(path)
+	if err != nil {
+		return 0, false
+	}
+	return pid, pidfile.Locked(path)
+}
+
+// queryStatus gets the status from the admin API
+func queryStatus(cCtx *cli.Context, address string) (*common.Status, error) {
+	resp, err := adminRequest(cCtx, address, http.MethodGet, "/status")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the admin API answered %s", resp.Status)
+	}
+
+	status := common.Status{}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("could not decode the status: %v", err)
+	}
+	return &status, nil
+}
+
+func printStatus(cCtx *cli.Context, status *common.Status) error {
+	healthy := 0
+	for _, backend := range status.Database {
+		if backend.Healthy {
+			healthy++
+		}
+	}
+
+	if cCtx.Bool("json") {
+		data, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		fmt.Printf("OCSP responder is running (pid %d)\n", status.PID)
+		fmt.Printf("Started at:  %s (up %s)\n", status.StartedAt.Format(time.RFC3339), status.Uptime)
+		fmt.Printf("Listening:   %s\n", strings.Join(status.Listen, ", "))
+		fmt.Printf("Admin API:   %s\n", strings.Join(status.AdminListen, ", "))
+		if status.Ready {
+			fmt.Printf("Ready:       yes\n")
+		} else {
+			fmt.Printf("Ready:       no, %s\n", status.NotReady)
+		}
+		if status.Signer != nil {
+			fmt.Printf("Signer:      %s (serial %s, expires %s)\n", status.Signer.Subject, status.Signer.Serial, status.Signer.NotAfter.Format(time.RFC3339))
+		}
+		for _, backend := range status.Database {
+			state := "healthy"
+			if !backend.Healthy {
+				state = "down: " + backend.LastError
+			}
+			fmt.Printf("Database:    %s %s\n", backend.Name, state)
+		}
+		names := []string{}
+		for name := range status.Counters {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			fmt.Printf("Counter:     %s %d\n", name, status.Counters[name])
+		}
+	}
+
+	if !status.Ready || healthy < len(status.Database) || len(status.Database) == 0 {
+		return cli.Exit("", statusDegraded)
+	}
+	return nil
+}
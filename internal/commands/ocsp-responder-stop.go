@@ -1,11 +1,13 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
-	"strconv"
+	"time"
 
+	"github.com/open-uem/openuem-ocsp-responder/internal/pidfile"
 	"github.com/urfave/cli/v2"
 )
 
@@ -14,18 +16,36 @@ func StopOCSPResponder() *cli.Command {
 		Name:   "stop",
 		Usage:  "Stop OCSP Responder server",
 		Action: stopOCSPResponder,
+		Flags: []cli.Flag{
+			PIDFileFlag(),
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "the time to wait for the responder to exit",
+				Value: 30 * time.Second,
+			},
+		},
 	}
 }
 
 func stopOCSPResponder(cCtx *cli.Context) error {
-	pidByte, err := os.ReadFile("PIDFILE")
+	path := cCtx.String("pidfile")
+
+	pid, err := pidfile.Read(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("could not find the PID file %s, the OCSP responder is not running", path)
+	}
 	if err != nil {
-		return fmt.Errorf("could not find the PIDFILE")
+		return err
 	}
 
-	pid, err := strconv.Atoi(string(pidByte))
-	if err != nil {
-		return fmt.Errorf("could not parse the pid from PIDFILE")
+	// Only the running instance holds the lock, so the pid is not signalled
+	// if it has been reused by another process
+	switch err := pidfile.RemoveStale(path); {
+	case err == nil:
+		log.Printf("[WARN]: the OCSP responder is not running, removed the stale PID file of process %d", pid)
+		return nil
+	case !errors.Is(err, pidfile.ErrLocked):
+		return err
 	}
 
 	p, err := os.FindProcess(pid)
@@ -37,10 +57,21 @@ func stopOCSPResponder(cCtx *cli.Context) error {
 		return fmt.Errorf("could not terminate the process associated with OCSP Responder, reason: %s", err.Error())
 	}
 
-	log.Printf("👋 Done! Your OCSP responder has stopped listening\n\n")
+	// The lock is released when the process exits
+	deadline := time.Now().Add(cCtx.Duration("timeout"))
+	for pidfile.Locked(path) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the OCSP responder (pid %d) has not stopped after %s", pid, cCtx.Duration("timeout"))
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
 
-	if err := os.Remove("PIDFILE"); err != nil {
+	// The PID file is removed by the responder, unless it didn't exit
+	// cleanly. It's locked again if another instance has started since
+	if err := pidfile.RemoveStale(path); err != nil && !errors.Is(err, os.ErrNotExist) && !errors.Is(err, pidfile.ErrLocked) {
 		return err
 	}
+
+	log.Printf("👋 Done! Your OCSP responder has stopped listening\n\n")
 	return nil
 }
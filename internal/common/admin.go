@@ -0,0 +1,184 @@
+package common
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/open-uem/openuem-ocsp-responder/internal/server"
+	"github.com/open-uem/openuem-ocsp-responder/internal/server/handler"
+)
+
+// Config is the effective configuration reported by the admin API. Secrets
+// are never included
+type Config struct {
+	ConfigFile        string   `json:"config_file,omitempty"`
+	DBUrl             string   `json:"dburl"`
+	DBReplicaUrls     []string `json:"dburl_replicas"`
+	DBMaxOpenConns    int      `json:"db_max_open_conns"`
+	DBMaxIdleConns    int      `json:"db_max_idle_conns"`
+	DBConnMaxLifetime string   `json:"db_conn_max_lifetime"`
+	DBQueryTimeout    string   `json:"db_query_timeout"`
+	CACert            string   `json:"cacert"`
+	OCSPCert          string   `json:"cert"`
+	OCSPKey           string   `json:"key"`
+	OCSPKeyEncrypted  bool     `json:"key_passphrase_set"`
+	PKCS11Module      string   `json:"pkcs11_module,omitempty"`
+	PKCS11Slot        int      `json:"pkcs11_slot,omitempty"`
+	PKCS11TokenLabel  string   `json:"pkcs11_token_label,omitempty"`
+	PKCS11KeyLabel    string   `json:"pkcs11_key_label,omitempty"`
+	ResponderID       string   `json:"responder_id"`
+	SignatureAlgo     string   `json:"signature_algorithm,omitempty"`
+	CertIDHashes      []string `json:"cert_id_hashes"`
+	RequestSignature  string   `json:"request_signature"`
+	RequestTrustStore string   `json:"request_trust_store,omitempty"`
+	Listen            []string `json:"listen"`
+	CacheDir          string   `json:"cache_dir"`
+	CacheMaxEntries   int      `json:"cache_max_entries"`
+	AdminListen       []string `json:"admin_listen"`
+	AdminMutualTLS    bool     `json:"admin_mutual_tls"`
+}
+
+// RedactedConfig returns the effective configuration with the database
+// passwords and every other secret removed
+func (w *Worker) RedactedConfig() Config {
+	replicas := []string{}
+	for _, replica := range w.DBReplicaUrls {
+		replicas = append(replicas, redactURL(replica))
+	}
+
+	// Certificates and keys set inline have no path
+	caCert, ocspCert, ocspKey := w.CACertPath, w.OCSPCertPath, w.OCSPKeyPath
+	if caCert == "" {
+		caCert = inlinePEM
+	}
+	if ocspCert == "" {
+		ocspCert = inlinePEM
+	}
+	if w.OCSPKeyPEM != "" {
+		ocspKey = inlinePEM
+	}
+
+	signatureAlgorithm := ""
+	if w.ResponseOptions.SignatureAlgorithm != x509.UnknownSignatureAlgorithm {
+		signatureAlgorithm = w.ResponseOptions.SignatureAlgorithm.String()
+	}
+
+	return Config{
+		ConfigFile:        w.ConfigFile,
+		DBUrl:             redactURL(w.DBUrl),
+		DBReplicaUrls:     replicas,
+		DBMaxOpenConns:    w.DBOptions.MaxOpenConns,
+		DBMaxIdleConns:    w.DBOptions.MaxIdleConns,
+		DBConnMaxLifetime: w.DBOptions.ConnMaxLifetime.String(),
+		DBQueryTimeout:    w.DBOptions.QueryTimeout.String(),
+		CACert:            caCert,
+		OCSPCert:          ocspCert,
+		OCSPKey:           ocspKey,
+		OCSPKeyEncrypted:  w.OCSPKeyPassphrase != "",
+		PKCS11Module:      w.PKCS11.Module,
+		PKCS11Slot:        w.PKCS11.Slot,
+		PKCS11TokenLabel:  w.PKCS11.TokenLabel,
+		PKCS11KeyLabel:    w.PKCS11.KeyLabel,
+		ResponderID:       w.ResponseOptions.ResponderID.String(),
+		SignatureAlgo:     signatureAlgorithm,
+		CertIDHashes:      hashNames(w.HandlerOptions.CertIDHashes),
+		RequestSignature:  w.HandlerOptions.RequestSignature.String(),
+		RequestTrustStore: w.RequestTrustStorePath,
+		Listen:            w.ListenAddresses(),
+		CacheDir:          w.CacheDir,
+		CacheMaxEntries:   w.CacheMaxEntries,
+		AdminListen:       w.AdminListen,
+		AdminMutualTLS:    w.AdminClientCAPath != "",
+	}
+}
+
+// StartAdminService starts the admin API if listen addresses are configured.
+// An admin API that can't be set up is an error, the responder doesn't run
+// without it as the status and reload commands rely on it
+func (w *Worker) StartAdminService() error {
+	if len(w.AdminListen) == 0 || w.WebServer == nil {
+		return nil
+	}
+
+	tlsConfig, err := w.adminTLSConfig()
+	if err != nil {
+		return fmt.Errorf("could not configure TLS for the admin API: %v", err)
+	}
+
+	h := &handler.AdminHandler{
+		Handler: w.WebServer.Handler,
+		Config:  func() any { return w.RedactedConfig() },
+		Reload:  w.Reload,
+		Status:  func() any { return w.Status() },
+	}
+
+	w.AdminServer, err = server.NewAdmin(h, w.AdminListen, tlsConfig)
+	if err != nil {
+		return fmt.Errorf("could not create the admin API server: %v", err)
+	}
+
+	go func() {
+		if err := w.AdminServer.Serve(); err != http.ErrServerClosed {
+			log.Printf("[ERROR]: the admin server has stopped, reason: %v", err.Error())
+			w.fail(err)
+		}
+	}()
+
+	log.Println("[INFO]: admin API is running")
+	return nil
+}
+
+// adminTLSConfig returns nil if no certificate is set for the admin API.
+// Client certificates are required if a client CA is set
+func (w *Worker) adminTLSConfig() (*tls.Config, error) {
+	if w.AdminCertPath == "" && w.AdminKeyPath == "" {
+		if w.AdminClientCAPath != "" {
+			return nil, errors.New("a certificate and a key are required to verify admin clients")
+		}
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(w.AdminCertPath, w.AdminKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the admin certificate and key: %v", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if w.AdminClientCAPath != "" {
+		pool, err := readCertPool(w.AdminClientCAPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the admin client CA: %v", err)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
+
+// keywordPassword matches the password of a Postgres keyword DSN, which may be
+// quoted
+var keywordPassword = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+func redactURL(dbUrl string) string {
+	if !strings.Contains(dbUrl, "://") && strings.Contains(dbUrl, "=") {
+		return keywordPassword.ReplaceAllString(dbUrl, "${1}xxxxx")
+	}
+
+	u, err := url.Parse(dbUrl)
+	if err != nil {
+		return "<invalid url>"
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,168 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+)
+
+// Backoff is the delay between the attempts to connect with the database or
+// to load the configuration. It doubles after every failed attempt up to Max,
+// and a random fraction of up to Jitter is added or removed so that several
+// responders don't retry at the same time
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+	Jitter  float64
+	// MaxAttempts is the number of attempts before giving up, 0 retries
+	// forever
+	MaxAttempts int
+}
+
+// DefaultBackoff returns the backoff used when none is configured
+func DefaultBackoff() Backoff {
+	return Backoff{
+		Initial: time.Second,
+		Max:     time.Minute,
+		Jitter:  0.2,
+	}
+}
+
+// Validate checks that the delays and the jitter can be used
+func (b Backoff) Validate() error {
+	switch {
+	case b.Initial <= 0:
+		return errors.New("the initial retry delay must be positive")
+	case b.Max < b.Initial:
+		return errors.New("the maximum retry delay can't be shorter than the initial one")
+	case b.Jitter < 0 || b.Jitter > 1:
+		return errors.New("the retry jitter must be between 0 and 1")
+	case b.MaxAttempts < 0:
+		return errors.New("the maximum number of attempts can't be negative")
+	}
+	return nil
+}
+
+// Delay returns the delay before the attempt following the failed attempt
+// number attempt, counted from 1
+func (b Backoff) Delay(attempt int) time.Duration {
+	d := b.Initial
+	for i := 1; i < attempt && d < b.Max; i++ {
+		d *= 2
+	}
+	d = min(d, b.Max)
+
+	if b.Jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * b.Jitter * float64(d))
+	}
+	return min(d, b.Max)
+}
+
+// RetryState is the state of an operation retried with a backoff, it's
+// reported by the health endpoint and the admin API
+type RetryState struct {
+	Done        bool       `json:"done"`
+	Attempts    int        `json:"attempts"`
+	LastError   string     `json:"last_error,omitempty"`
+	NextAttempt *time.Time `json:"next_attempt,omitempty"`
+	GaveUp      bool       `json:"gave_up,omitempty"`
+}
+
+// Err describes why the operation hasn't succeeded yet, it's nil once done
+func (s RetryState) Err(operation string) error {
+	switch {
+	case s.Done:
+		return nil
+	case s.GaveUp:
+		return fmt.Errorf("could not %s after %d attempts: %s", operation, s.Attempts, s.LastError)
+	case s.Attempts == 0:
+		return fmt.Errorf("trying to %s", operation)
+	case s.NextAttempt != nil:
+		return fmt.Errorf("could not %s, attempt %d failed: %s, next attempt at %s", operation, s.Attempts, s.LastError, s.NextAttempt.Format(time.RFC3339))
+	default:
+		return fmt.Errorf("could not %s, attempt %d failed: %s", operation, s.Attempts, s.LastError)
+	}
+}
+
+// retrier runs an operation until it succeeds, scheduling every new attempt
+// on the task scheduler as a one time job
+type retrier struct {
+	operation string
+	attempt   func() error
+	// backoff is read before every attempt so a reload can change it
+	backoff   func() Backoff
+	scheduler gocron.Scheduler
+	// onGiveUp is called once the maximum number of attempts is reached
+	onGiveUp func(error)
+
+	mu    sync.Mutex
+	state RetryState
+}
+
+// State returns the state of the operation
+func (r *retrier) State() RetryState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state
+}
+
+// run makes an attempt and, if it fails, schedules the next one after the
+// delay of the backoff
+func (r *retrier) run() {
+	r.result(r.attempt())
+}
+
+// result records the result of an attempt, which may have been made before
+// the retrier was created, and schedules the next one if it failed
+func (r *retrier) result(err error) {
+	backoff := r.backoff()
+
+	r.mu.Lock()
+	r.state.NextAttempt = nil
+	if err == nil {
+		r.state.Done = true
+		r.state.LastError = ""
+		r.mu.Unlock()
+		return
+	}
+
+	r.state.Attempts++
+	r.state.LastError = err.Error()
+	attempts := r.state.Attempts
+
+	if backoff.MaxAttempts > 0 && attempts >= backoff.MaxAttempts {
+		r.state.GaveUp = true
+		err := r.state.Err(r.operation)
+		r.mu.Unlock()
+
+		log.Printf("[ERROR]: %v, giving up", err)
+		r.giveUp(err)
+		return
+	}
+
+	delay := backoff.Delay(attempts)
+	next := time.Now().Add(delay)
+	r.state.NextAttempt = &next
+	r.mu.Unlock()
+
+	log.Printf("[ERROR]: could not %s, attempt %d, retrying in %s: %v", r.operation, attempts, delay.Round(time.Millisecond), err)
+
+	if _, err := r.scheduler.NewJob(
+		gocron.OneTimeJob(gocron.OneTimeJobStartDateTime(next)),
+		gocron.NewTask(r.run),
+	); err != nil {
+		log.Printf("[ERROR]: could not schedule the next attempt to %s: %v", r.operation, err)
+		r.giveUp(err)
+	}
+}
+
+func (r *retrier) giveUp(err error) {
+	if r.onGiveUp != nil {
+		r.onGiveUp(err)
+	}
+}
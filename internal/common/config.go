@@ -1,104 +1,236 @@
 package common
 
 import (
+	"crypto/x509"
+	"errors"
+	"fmt"
 	"log"
-	"time"
 
-	"github.com/go-co-op/gocron/v2"
-	"github.com/open-uem/utils"
-	"gopkg.in/ini.v1"
+	"github.com/open-uem/openuem-ocsp-responder/internal/signer"
 )
 
+// ErrDatabaseURLNotSet is returned if no layer of the configuration sets the
+// database url, which has no default
+var ErrDatabaseURLNotSet = errors.New("the database url is not set, use --dburl, DATABASE_URL or the URL key of [DB]")
+
+// GenerateOCSPResponderConfig generates the configuration of the service from
+// the configuration file of the OpenUEM server, or the one set in
+// OCSP_CONFIG, and the environment
 func (w *Worker) GenerateOCSPResponderConfig() error {
-	var err error
+	s, configFile, err := NewServiceSettings()
+	if err != nil {
+		return err
+	}
+	return w.generateConfig(s, configFile)
+}
+
+// generateConfig sets every option of the responder from the settings
+func (w *Worker) generateConfig(s *Settings, configFile string) error {
+	w.ConfigFile = configFile
 
-	// Get config file location
-	configFile := utils.GetConfigFile()
+	steps := []func(*Settings) error{
+		w.loadDatabaseConfig,
+		w.loadCertificates,
+		w.loadSigningKey,
+		w.loadHTTPConfig,
+		w.loadAdminConfig,
+	}
+	for _, step := range steps {
+		if err := step(s); err != nil {
+			return err
+		}
+	}
+
+	return s.Err()
+}
+
+func (w *Worker) loadDatabaseConfig(s *Settings) error {
+	var err error
 
-	// Get new OCSP Responder
-	w.DBUrl, err = utils.CreatePostgresDatabaseURL()
+	w.DBUrl, _, err = s.databaseURL()
 	if err != nil {
 		log.Printf("[ERROR]: %v", err)
 		return err
 	}
+	if w.DBUrl == "" {
+		return ErrDatabaseURLNotSet
+	}
 
-	// Open ini file
-	cfg, err := ini.Load(configFile)
-	if err != nil {
+	// Read replicas are optional
+	w.DBReplicaUrls = s.Strings("dburl-replica")
+
+	w.DBOptions.MaxOpenConns = s.Int("db-max-open-conns")
+	w.DBOptions.MaxIdleConns = s.Int("db-max-idle-conns")
+	w.DBOptions.ConnMaxLifetime = s.Duration("db-conn-max-lifetime")
+	w.DBOptions.QueryTimeout = s.Duration("db-query-timeout")
+
+	// The connection is retried with an exponential backoff, it's only
+	// replaced if valid as the retries in progress read it
+	retry := Backoff{
+		Initial:     s.Duration("db-retry-initial"),
+		Max:         s.Duration("db-retry-max"),
+		Jitter:      s.Float64("db-retry-jitter"),
+		MaxAttempts: s.Int("db-retry-max-attempts"),
+	}
+	if err := retry.Validate(); err != nil {
 		return err
 	}
+	w.DBRetry = retry
 
-	key, err := cfg.Section("Certificates").GetKey("CACert")
-	if err != nil {
-		return err
+	// The liveness probe is disabled with a zero interval
+	probeInterval := s.Duration("db-probe-interval")
+	if probeInterval < 0 {
+		return errors.New("the database probe interval can't be negative")
 	}
+	w.DBProbeInterval = probeInterval
+	return nil
+}
+
+func (w *Worker) loadCertificates(s *Settings) error {
+	var err error
 
-	w.CACert, err = utils.ReadPEMCertificate(key.String())
+	// Certificates set inline in PEM format take precedence over the files
+	w.CACertPath, w.CACert, err = loadCertificate(s, "cacert")
 	if err != nil {
-		log.Printf("[ERROR]: could not read CA certificate in %s", key.String())
+		log.Printf("[ERROR]: could not read CA certificate: %v", err)
 		return err
 	}
 
-	key, err = cfg.Section("Certificates").GetKey("OCSPCert")
+	w.OCSPCertPath, w.OCSPCert, err = loadCertificate(s, "cert")
 	if err != nil {
+		log.Printf("[ERROR]: could not read OCSP certificate: %v", err)
 		return err
 	}
+	return nil
+}
+
+// loadCertificate reads the certificate of the setting name, from its PEM
+// setting if set, in which case the returned path is empty, or from its file
+func loadCertificate(s *Settings, name string) (string, *x509.Certificate, error) {
+	if data := s.String(name + "-pem"); data != "" {
+		cert, err := parsePEMCertificate([]byte(data))
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid --%s-pem: %v", name, err)
+		}
+		return "", cert, nil
+	}
+
+	path := s.Path(name)
+	cert, err := readPEMCertificate(path)
+	return path, cert, err
+}
 
-	w.OCSPCert, err = utils.ReadPEMCertificate(key.String())
+func (w *Worker) loadSigningKey(s *Settings) error {
+	var err error
+
+	// The signing key is read from a PKCS#11 token if a module is configured
+	w.PKCS11 = signer.PKCS11Config{
+		Module:     s.String("pkcs11-module"),
+		Slot:       s.Int("pkcs11-slot"),
+		TokenLabel: s.String("pkcs11-token-label"),
+		KeyLabel:   s.String("pkcs11-key-label"),
+	}
+	w.PKCS11.PIN, err = signer.ReadSecret(s.String("pkcs11-pin"), s.Path("pkcs11-pin-file"), "")
 	if err != nil {
-		log.Println("[ERROR]: could not read OCSP certificate")
 		return err
 	}
 
-	key, err = cfg.Section("Certificates").GetKey("OCSPKey")
-	if err != nil {
+	w.OCSPKeyPath = ""
+	w.OCSPKeyPEM = ""
+	w.OCSPKeyPassphrase = ""
+	if !w.PKCS11.Enabled() {
+		// A key set inline in PEM format takes precedence over the file
+		w.OCSPKeyPEM = s.String("key-pem")
+		if w.OCSPKeyPEM == "" {
+			w.OCSPKeyPath = s.Path("key")
+		}
+
+		w.OCSPKeyPassphrase, err = signer.ReadSecret(s.String("key-passphrase"), s.Path("key-passphrase-file"), s.String("key-passphrase-credential"))
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := w.loadSigner(); err != nil {
+		log.Printf("[ERROR]: could not read OCSP private key: %v", err)
 		return err
 	}
 
-	w.OCSPPrivateKey, err = utils.ReadPEMPrivateKey(key.String())
+	// Responses are identified by the OCSP certificate subject by default
+	w.ResponseOptions.ResponderID, err = signer.ParseResponderID(s.String("responder-id"))
 	if err != nil {
-		log.Println("[ERROR]: could not read OCSP private key")
 		return err
 	}
 
-	key, err = cfg.Section("OCSP").GetKey("OCSPPort")
+	// The signature algorithm is chosen from the key type if not set
+	w.ResponseOptions.SignatureAlgorithm, err = signer.ParseSignatureAlgorithm(s.String("signature-algorithm"))
 	if err != nil {
 		return err
 	}
-
-	w.Port = key.String()
-
+	if err := w.ResponseOptions.Validate(w.OCSPPrivateKey.Public()); err != nil {
+		log.Printf("[ERROR]: the signature algorithm can't be used with the OCSP private key: %v", err)
+		return err
+	}
 	return nil
 }
 
-func (w *Worker) StartGenerateOCSPResponderConfigJob() error {
+func (w *Worker) loadHTTPConfig(s *Settings) error {
 	var err error
 
-	// Create task for getting the worker config
-	w.ConfigJob, err = w.TaskScheduler.NewJob(
-		gocron.DurationJob(
-			time.Duration(time.Duration(1*time.Minute)),
-		),
-		gocron.NewTask(
-			func() {
-				err = w.GenerateOCSPResponderConfig()
-				if err != nil {
-					log.Printf("[ERROR]: could not generate config for OCSP responder, reason: %v", err)
-					return
-				}
-
-				log.Println("[INFO]: responder's config has been successfully generated")
-				if err := w.TaskScheduler.RemoveJob(w.ConfigJob.ID()); err != nil {
-					return
-				}
-				return
-			},
-		),
-	)
+	// If listen addresses are set the port is ignored
+	w.Port = s.String("port")
+	w.Listen = s.Strings("listen")
+
+	w.HandlerOptions.PathPrefix = s.String("path-prefix")
+	w.HandlerOptions.LenientContentType = s.Bool("lenient-content-type")
+	w.HandlerOptions.CertIDHashes, err = ParseHashAlgorithms(s.Strings("cert-id-hash"))
 	if err != nil {
-		log.Fatalf("[FATAL]: could not start the generate OCSP responder config job: %v", err)
 		return err
 	}
-	log.Printf("[INFO]: new generate OCSP responder config job has been scheduled every %d minute", 1)
+
+	// Signed requests are ignored unless a policy is set
+	w.RequestTrustStorePath = s.Path("request-trust-store")
+	if err := w.setRequestSignaturePolicy(s.String("request-signature")); err != nil {
+		log.Printf("[ERROR]: could not configure the request signature policy: %v", err)
+		return err
+	}
+
+	w.HandlerOptions.Limits.ClientRate = s.Float64("rate-limit")
+	w.HandlerOptions.Limits.ClientBurst = s.Int("rate-burst")
+	w.HandlerOptions.Limits.GlobalRate = s.Float64("global-rate-limit")
+	w.HandlerOptions.Limits.GlobalBurst = s.Int("global-rate-burst")
+	w.HandlerOptions.Limits.MaxConcurrentSigning = s.Int("max-concurrent-signing")
+	w.HandlerOptions.Limits.MaxRequestSize = s.Int64("max-request-size")
+
+	// The directory where the last known good responses are saved is optional
+	w.CacheDir = s.Path("cache-dir")
+	w.CacheMaxEntries = s.Int("cache-max-entries")
+	if w.CacheMaxEntries < 0 {
+		return errors.New("the maximum number of cached responses can't be negative")
+	}
 	return nil
 }
+
+func (w *Worker) loadAdminConfig(s *Settings) error {
+	// The admin listener is only started if addresses are set
+	w.AdminListen = s.Strings("admin-listen")
+	w.AdminCertPath = s.Path("admin-cert")
+	w.AdminKeyPath = s.Path("admin-key")
+	w.AdminClientCAPath = s.Path("admin-client-ca")
+	return nil
+}
+
+// StartGenerateOCSPResponderConfigJob retries loading the configuration of
+// the service, which failed with err, on the task scheduler with the backoff
+// used for the database. Each attempt reloads the configuration so the
+// responder, if already started, uses it
+func (w *Worker) StartGenerateOCSPResponderConfigJob(err error) {
+	w.configLoad = &retrier{
+		operation: "load the configuration",
+		attempt:   w.Reload,
+		backoff:   w.retryBackoff,
+		scheduler: w.TaskScheduler,
+		onGiveUp:  w.fail,
+	}
+	w.configLoad.result(err)
+}
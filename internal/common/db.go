@@ -1,72 +1,134 @@
 package common
 
 import (
-	"fmt"
+	"errors"
 	"log"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/go-co-op/gocron/v2"
+	"github.com/open-uem/openuem-ocsp-responder/internal/cache"
+	"github.com/open-uem/openuem-ocsp-responder/internal/metrics"
 	"github.com/open-uem/openuem-ocsp-responder/internal/models"
 	"github.com/open-uem/openuem-ocsp-responder/internal/server"
 )
 
-func (w *Worker) StartDBConnectJob() error {
-	var err error
+// StartDBConnectJob connects with the database. If it can't be reached the
+// connection is retried on the task scheduler with an exponential backoff,
+// until it succeeds or the maximum number of attempts is reached
+func (w *Worker) StartDBConnectJob() {
+	w.dbConnect.run()
+}
 
-	w.Model, err = models.New(w.DBUrl)
-	if err == nil {
-		log.Println("[INFO]: connection established with database")
+// connectDatabase opens the database and hands it to the web server, the
+// previous connection, if any, is closed
+func (w *Worker) connectDatabase() error {
+	w.reloadMu.Lock()
+	dbUrl, replicaUrls, options := w.DBUrl, w.DBReplicaUrls, w.DBOptions
+	w.reloadMu.Unlock()
 
-		w.StartOCSPResponderWebService()
-		return nil
+	if dbUrl == "" {
+		return ErrDatabaseURLNotSet
 	}
-	log.Printf("[ERROR]: could not connect with database %v", err)
-
-	// Create task for running the agent
-	w.DBConnectJob, err = w.TaskScheduler.NewJob(
-		gocron.DurationJob(
-			time.Duration(time.Duration(30*time.Second)),
-		),
-		gocron.NewTask(
-			func() {
-				w.Model, err = models.New(w.DBUrl)
-				if err != nil {
-					log.Printf("[ERROR]: could not connect with database %v", err)
-					return
-				}
-				log.Println("[INFO]: connection established with database")
-
-				if err := w.TaskScheduler.RemoveJob(w.DBConnectJob.ID()); err != nil {
-					return
-				}
-
-				w.StartOCSPResponderWebService()
-			},
-		),
-	)
+
+	model, err := models.New(dbUrl, replicaUrls, options)
 	if err != nil {
-		log.Fatalf("[FATAL]: could not start the DB connect job: %v", err)
 		return err
 	}
-	log.Printf("[INFO]: new DB connect job has been scheduled every %d seconds", 30)
+
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	// The configuration may have been reloaded while connecting
+	if w.DBUrl != dbUrl || !slices.Equal(w.DBReplicaUrls, replicaUrls) || w.DBOptions != options {
+		model.Close()
+		return errors.New("the database settings changed while connecting")
+	}
+
+	prev := w.Model
+	w.Model = model
+	if w.WebServer != nil {
+		w.WebServer.Handler.SetModel(model)
+	}
+	if prev != nil {
+		prev.Close()
+	}
+
+	metrics.DatabaseUp.Set(1)
+	log.Println("[INFO]: connection established with database, the OCSP responder is ready")
 	return nil
 }
 
 func (w *Worker) StartOCSPResponderWebService() {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
 	log.Println("[INFO]: launching server")
 
-	port := ":8000"
-	if w.Port != "" {
-		port = fmt.Sprintf(":%s", w.Port)
+	addresses := w.ListenAddresses()
+
+	if w.Cache == nil {
+		c, err := cache.New(w.CacheDir, w.CacheMaxEntries)
+		if err != nil {
+			log.Printf("[ERROR]: could not load the response cache, responses will be cached in memory only: %v", err)
+			c, _ = cache.New("", w.CacheMaxEntries)
+		}
+		w.Cache = c
 	}
-	w.WebServer = server.New(w.Model, port, w.CACert, w.OCSPCert, w.OCSPPrivateKey)
+
+	w.WebServer = server.New(w.Model, addresses, w.CACert, w.OCSPCert, w.OCSPPrivateKey, w.ResponseOptions, w.Cache, w.HandlerOptions)
+	w.WebServer.Handler.Readiness = w.Ready
 
 	go func() {
 		if err := w.WebServer.Serve(); err != http.ErrServerClosed {
 			log.Printf("[ERROR]: the server has stopped, reason: %v", err.Error())
+			w.fail(err)
 		}
 	}()
 
 	log.Println("[INFO]: OCSP responder is running")
+
+	if err := w.StartAdminService(); err != nil {
+		log.Printf("[ERROR]: %v", err)
+		w.fail(err)
+	}
+}
+
+// cachePruneInterval is how often the expired responses are removed from the
+// cache
+const cachePruneInterval = 10 * time.Minute
+
+// StartCachePruneJob removes the expired responses from the cache
+// periodically, so they don't pile up in memory and on disk
+func (w *Worker) StartCachePruneJob() {
+	if w.Cache == nil {
+		return
+	}
+
+	_, err := w.TaskScheduler.NewJob(
+		gocron.DurationJob(cachePruneInterval),
+		gocron.NewTask(func() {
+			if removed := w.Cache.Prune(); removed > 0 {
+				log.Printf("[INFO]: %d expired responses have been removed from the cache", removed)
+			}
+		}),
+	)
+	if err != nil {
+		log.Printf("[ERROR]: could not start the cache prune job: %v", err)
+	}
+}
+
+// ListenAddresses returns the configured listen addresses or, if none is
+// set, the port on every interface
+func (w *Worker) ListenAddresses() []string {
+	if len(w.Listen) > 0 {
+		return w.Listen
+	}
+
+	port := "8000"
+	if w.Port != "" {
+		port = w.Port
+	}
+	return []string{":" + port}
 }
@@ -0,0 +1,51 @@
+package common
+
+import (
+	"crypto"
+	"fmt"
+	"strings"
+)
+
+// ParseHashAlgorithm returns the hash algorithm matching a name such as
+// sha1 or SHA-256
+func ParseHashAlgorithm(name string) (crypto.Hash, error) {
+	switch strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "-", "") {
+	case "sha1":
+		return crypto.SHA1, nil
+	case "sha256":
+		return crypto.SHA256, nil
+	case "sha384":
+		return crypto.SHA384, nil
+	case "sha512":
+		return crypto.SHA512, nil
+	default:
+		return 0, fmt.Errorf("unsupported hash algorithm %q", name)
+	}
+}
+
+// ParseHashAlgorithms returns the hash algorithms matching a list of names
+func ParseHashAlgorithms(names []string) ([]crypto.Hash, error) {
+	hashes := []crypto.Hash{}
+	for _, name := range names {
+		hash, err := ParseHashAlgorithm(name)
+		if err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+// hashNames returns the names of the hash algorithms
+func hashNames(hashes []crypto.Hash) []string {
+	names := []string{}
+	for _, hash := range hashes {
+		names = append(names, hashName(hash))
+	}
+	return names
+}
+
+// hashName returns the name used for the hash algorithm in paths and config
+func hashName(hash crypto.Hash) string {
+	return strings.ToLower(strings.ReplaceAll(hash.String(), "-", ""))
+}
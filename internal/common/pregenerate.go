@@ -0,0 +1,159 @@
+package common
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/open-uem/openuem-ocsp-responder/internal/server/handler"
+	"github.com/open-uem/openuem-ocsp-responder/internal/signer"
+	"golang.org/x/crypto/ocsp"
+)
+
+// PregenerateResponses signs a response for every known serial and saves it
+// in dir twice: under the RFC 5019 GET path (the base64 encoded request) so
+// it can be served by a static file server, and under by-serial/<hash>/<serial>.der.
+// The responses left by a previous run for serials or hashes that are no longer
+// pregenerated are removed afterwards
+func (w *Worker) PregenerateResponses(dir string, hashes []crypto.Hash) error {
+	ctx := context.Background()
+
+	serials, err := w.Model.GetKnownSerials(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get the known serials: %v", err)
+	}
+
+	written := map[string]bool{}
+	h := handler.NewHandler(w.Model, w.CACert, w.OCSPCert, w.OCSPPrivateKey, w.ResponseOptions, nil)
+
+	for _, serial := range serials {
+		for _, hash := range hashes {
+			cert := &x509.Certificate{SerialNumber: big.NewInt(serial)}
+			requestBytes, err := ocsp.CreateRequest(cert, w.CACert, &ocsp.RequestOptions{Hash: hash})
+			if err != nil {
+				return fmt.Errorf("could not create request for serial %d: %v", serial, err)
+			}
+
+			req, err := ocsp.ParseRequest(requestBytes)
+			if err != nil {
+				return fmt.Errorf("could not parse request for serial %d: %v", serial, err)
+			}
+
+			responseTemplate, _, err := h.CreateResponseTemplate(ctx, req)
+			if err != nil {
+				return fmt.Errorf("could not check the revocation status for serial %d: %v", serial, err)
+			}
+
+			response, err := signer.CreateResponse(w.CACert, w.OCSPCert, responseTemplate, w.OCSPPrivateKey, w.ResponseOptions)
+			if err != nil {
+				return fmt.Errorf("could not sign response for serial %d: %v", serial, err)
+			}
+
+			getPath := filepath.Join(dir, filepath.FromSlash(base64.StdEncoding.EncodeToString(requestBytes)))
+			if err := writeFileAtomic(getPath, response); err != nil {
+				return err
+			}
+			written[getPath] = true
+
+			serialPath := filepath.Join(dir, "by-serial", hashName(hash), fmt.Sprintf("%X.der", serial))
+			if err := writeFileAtomic(serialPath, response); err != nil {
+				return err
+			}
+			written[serialPath] = true
+		}
+	}
+
+	log.Printf("[INFO]: %d responses have been pregenerated in %s", len(serials)*len(hashes), dir)
+
+	removed, err := removeOrphanedResponses(dir, written)
+	if err != nil {
+		return fmt.Errorf("could not remove orphaned responses: %v", err)
+	}
+	if removed > 0 {
+		log.Printf("[INFO]: %d orphaned responses have been removed from %s", removed, dir)
+	}
+	return nil
+}
+
+// removeOrphanedResponses removes the responses in dir that haven't been
+// written in this run. Only the files that look like a pregenerated response
+// are removed, so other files published alongside them are kept
+func removeOrphanedResponses(dir string, written map[string]bool) (int, error) {
+	removed := 0
+	dirs := []string{}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir {
+				dirs = append(dirs, path)
+			}
+			return nil
+		}
+		if written[path] || !isPregeneratedResponse(dir, path) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	if err != nil {
+		return removed, err
+	}
+
+	// Directories emptied by the removal go too, the deepest first. Those that
+	// still have files can't be removed, which is fine
+	for i := len(dirs) - 1; i >= 0; i-- {
+		_ = os.Remove(dirs[i])
+	}
+
+	return removed, nil
+}
+
+// isPregeneratedResponse reports whether path is a response under by-serial or
+// a base64 encoded OCSP request
+func isPregeneratedResponse(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+
+	if strings.HasPrefix(rel, "by-serial/") {
+		return strings.HasSuffix(rel, ".der") || strings.HasSuffix(rel, ".der.tmp")
+	}
+
+	requestBytes, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(rel, ".tmp"))
+	if err != nil {
+		return false
+	}
+	_, err = ocsp.ParseRequest(requestBytes)
+	return err == nil
+}
+
+// writeFileAtomic writes data to a temporary file that is renamed afterwards
+// so a static file server never serves a partially written response
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("could not create directory for %s: %v", path, err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("could not write %s: %v", path, err)
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,99 @@
+package common
+
+import (
+	"context"
+	"log"
+
+	"github.com/go-co-op/gocron/v2"
+	"github.com/open-uem/openuem-ocsp-responder/internal/metrics"
+)
+
+// StartDBProbeJob checks the connection with the database periodically. When
+// no database answers the responder is degraded, requests are answered from
+// the cache or with tryLater, and the database is opened again with the
+// backoff of the connection until it's back
+func (w *Worker) StartDBProbeJob() {
+	if w.DBProbeInterval <= 0 {
+		log.Println("[INFO]: the database liveness probe is disabled")
+		return
+	}
+
+	_, err := w.TaskScheduler.NewJob(
+		gocron.DurationJob(w.DBProbeInterval),
+		gocron.NewTask(w.probeDatabase),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		log.Printf("[ERROR]: could not start the database probe job: %v", err)
+		return
+	}
+	log.Printf("[INFO]: new database probe job has been scheduled every %s", w.DBProbeInterval)
+}
+
+func (w *Worker) probeDatabase() {
+	// The database isn't probed until connected, nor while reconnecting
+	model := w.model()
+	if model == nil || w.reconnecting() {
+		return
+	}
+
+	if err := model.Probe(context.Background()); err != nil {
+		metrics.DatabaseProbeFailures.Inc()
+		w.databaseLost(err)
+		return
+	}
+	metrics.DatabaseUp.Set(1)
+}
+
+// databaseLost switches the responder to degraded mode and starts
+// reconnecting with the database
+func (w *Worker) databaseLost(err error) {
+	metrics.DatabaseLost.Inc()
+	metrics.DatabaseUp.Set(0)
+	log.Printf("[WARN]: lost the connection with the database, requests are answered from the cache or with tryLater until it's back: %v", err)
+
+	r := &retrier{
+		operation: "reconnect with the database",
+		attempt:   w.reconnectDatabase,
+		backoff:   w.reconnectBackoff,
+		scheduler: w.TaskScheduler,
+	}
+
+	w.dbMu.Lock()
+	w.dbReconnect = r
+	w.dbMu.Unlock()
+
+	// The failed probe is the first attempt
+	r.result(err)
+}
+
+func (w *Worker) reconnectDatabase() error {
+	if err := w.connectDatabase(); err != nil {
+		return err
+	}
+
+	metrics.DatabaseReconnects.Inc()
+	log.Println("[INFO]: the connection with the database has been re-established")
+	return nil
+}
+
+// reconnectBackoff is the backoff of the connection, but the responder never
+// gives up reconnecting once it has started
+func (w *Worker) reconnectBackoff() Backoff {
+	backoff := w.retryBackoff()
+	backoff.MaxAttempts = 0
+	return backoff
+}
+
+// reconnection returns the state of the reconnection with the database, if
+// it has ever been lost
+func (w *Worker) reconnection() *retrier {
+	w.dbMu.Lock()
+	defer w.dbMu.Unlock()
+	return w.dbReconnect
+}
+
+func (w *Worker) reconnecting() bool {
+	r := w.reconnection()
+	return r != nil && !r.State().Done
+}
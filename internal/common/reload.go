@@ -0,0 +1,235 @@
+package common
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/open-uem/openuem-ocsp-responder/internal/models"
+	"github.com/open-uem/openuem-ocsp-responder/internal/server/handler"
+	"github.com/open-uem/openuem-ocsp-responder/internal/signer"
+)
+
+// workerConfig is a copy of the settings that can change on a reload, it's
+// used to roll back to the previous configuration if the new one can't be
+// applied
+type workerConfig struct {
+	dbUrl                 string
+	dbReplicaUrls         []string
+	dbOptions             models.Options
+	dbRetry               Backoff
+	dbProbeInterval       time.Duration
+	caCertPath            string
+	caCert                *x509.Certificate
+	ocspCertPath          string
+	ocspCert              *x509.Certificate
+	ocspKeyPath           string
+	ocspKeyPEM            string
+	ocspPrivateKey        crypto.Signer
+	ocspKeyPassphrase     string
+	pkcs11                signer.PKCS11Config
+	responseOptions       signer.ResponseOptions
+	port                  string
+	listen                []string
+	cacheDir              string
+	cacheMaxEntries       int
+	handlerOptions        handler.Options
+	requestTrustStorePath string
+	adminListen           []string
+	adminCertPath         string
+	adminKeyPath          string
+	adminClientCAPath     string
+}
+
+func (w *Worker) saveConfig() workerConfig {
+	return workerConfig{
+		dbUrl:                 w.DBUrl,
+		dbReplicaUrls:         w.DBReplicaUrls,
+		dbOptions:             w.DBOptions,
+		dbRetry:               w.DBRetry,
+		dbProbeInterval:       w.DBProbeInterval,
+		caCertPath:            w.CACertPath,
+		caCert:                w.CACert,
+		ocspCertPath:          w.OCSPCertPath,
+		ocspCert:              w.OCSPCert,
+		ocspKeyPath:           w.OCSPKeyPath,
+		ocspKeyPEM:            w.OCSPKeyPEM,
+		ocspPrivateKey:        w.OCSPPrivateKey,
+		ocspKeyPassphrase:     w.OCSPKeyPassphrase,
+		pkcs11:                w.PKCS11,
+		responseOptions:       w.ResponseOptions,
+		port:                  w.Port,
+		listen:                w.Listen,
+		cacheDir:              w.CacheDir,
+		cacheMaxEntries:       w.CacheMaxEntries,
+		handlerOptions:        w.HandlerOptions,
+		requestTrustStorePath: w.RequestTrustStorePath,
+		adminListen:           w.AdminListen,
+		adminCertPath:         w.AdminCertPath,
+		adminKeyPath:          w.AdminKeyPath,
+		adminClientCAPath:     w.AdminClientCAPath,
+	}
+}
+
+func (w *Worker) restoreConfig(c workerConfig) {
+	w.DBUrl = c.dbUrl
+	w.DBReplicaUrls = c.dbReplicaUrls
+	w.DBOptions = c.dbOptions
+	w.DBRetry = c.dbRetry
+	w.DBProbeInterval = c.dbProbeInterval
+	w.CACertPath = c.caCertPath
+	w.CACert = c.caCert
+	w.OCSPCertPath = c.ocspCertPath
+	w.OCSPCert = c.ocspCert
+	w.OCSPKeyPath = c.ocspKeyPath
+	w.OCSPKeyPEM = c.ocspKeyPEM
+	w.OCSPPrivateKey = c.ocspPrivateKey
+	w.OCSPKeyPassphrase = c.ocspKeyPassphrase
+	w.PKCS11 = c.pkcs11
+	w.ResponseOptions = c.responseOptions
+	w.Port = c.port
+	w.Listen = c.listen
+	w.CacheDir = c.cacheDir
+	w.CacheMaxEntries = c.cacheMaxEntries
+	w.HandlerOptions = c.handlerOptions
+	w.RequestTrustStorePath = c.requestTrustStorePath
+	w.AdminListen = c.adminListen
+	w.AdminCertPath = c.adminCertPath
+	w.AdminKeyPath = c.adminKeyPath
+	w.AdminClientCAPath = c.adminClientCAPath
+}
+
+// Reload generates the configuration again and applies it to the running
+// responder: the database is opened again if its settings changed, the
+// listeners are rebound if the addresses changed and the signer is swapped.
+// If any step fails the previous configuration is kept
+func (w *Worker) Reload() error {
+	if w.LoadConfig == nil {
+		return errors.New("the configuration can't be reloaded")
+	}
+
+	// Reloads run one at a time, but the configuration lock is released
+	// while the database is opened so the requests and the status aren't
+	// blocked by a slow database
+	w.reloading.Lock()
+	defer w.reloading.Unlock()
+
+	w.reloadMu.Lock()
+	prev := w.saveConfig()
+	prevAddresses := w.ListenAddresses()
+
+	// the PKCS#11 session in use must stay open until the new signer is
+	// ready, so it's detached before the key is loaded again
+	prevCloser := w.signerCloser
+	w.signerCloser = nil
+
+	rollback := func() {
+		w.closeSigner()
+		w.signerCloser = prevCloser
+		w.restoreConfig(prev)
+	}
+
+	if err := w.LoadConfig(); err != nil {
+		rollback()
+		w.reloadMu.Unlock()
+		return err
+	}
+
+	// The cache, the database probe and the admin API are set up once, their
+	// settings are kept until the responder is restarted
+	if w.CacheDir != prev.cacheDir || w.CacheMaxEntries != prev.cacheMaxEntries || w.DBProbeInterval != prev.dbProbeInterval || !slices.Equal(w.AdminListen, prev.adminListen) ||
+		w.AdminCertPath != prev.adminCertPath || w.AdminKeyPath != prev.adminKeyPath || w.AdminClientCAPath != prev.adminClientCAPath {
+		log.Println("[WARN]: the cache directory, the database probe interval and the admin API settings are only applied when the responder is restarted")
+		w.CacheDir = prev.cacheDir
+		w.CacheMaxEntries = prev.cacheMaxEntries
+		w.DBProbeInterval = prev.dbProbeInterval
+		w.AdminListen = prev.adminListen
+		w.AdminCertPath = prev.adminCertPath
+		w.AdminKeyPath = prev.adminKeyPath
+		w.AdminClientCAPath = prev.adminClientCAPath
+	}
+
+	// The responder isn't running yet, the new settings will be used when
+	// the database is reachable
+	if w.WebServer == nil {
+		closeToken(prevCloser)
+		w.reloadMu.Unlock()
+		log.Println("[INFO]: the configuration has been reloaded")
+		return nil
+	}
+
+	reconnect := w.Model != nil && (w.DBUrl != prev.dbUrl || !slices.Equal(w.DBReplicaUrls, prev.dbReplicaUrls) || w.DBOptions != prev.dbOptions)
+	dbUrl, replicaUrls, options := w.DBUrl, w.DBReplicaUrls, w.DBOptions
+	w.reloadMu.Unlock()
+
+	var model *models.Model
+	if reconnect {
+		var err error
+		model, err = models.New(dbUrl, replicaUrls, options)
+		if err != nil {
+			w.reloadMu.Lock()
+			rollback()
+			w.reloadMu.Unlock()
+			return fmt.Errorf("could not connect with database: %v", err)
+		}
+	}
+
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	if addresses := w.ListenAddresses(); !slices.Equal(addresses, prevAddresses) {
+		if err := w.WebServer.Rebind(addresses); err != nil {
+			if model != nil {
+				model.Close()
+			}
+			rollback()
+			return err
+		}
+	}
+
+	h := w.WebServer.Handler
+	if model != nil {
+		// the connection may have been re-established with the new settings
+		// in the meantime, the latest model is kept
+		h.SetModel(model)
+		if w.Model != nil {
+			w.Model.Close()
+		}
+		w.Model = model
+		log.Println("[INFO]: connection established with database")
+	}
+	h.SetSigner(w.CACert, w.OCSPCert, w.OCSPPrivateKey, w.ResponseOptions)
+
+	// Responses signed with the previous certificates mustn't be served
+	if h.Cache != nil && (!w.CACert.Equal(prev.caCert) || !w.OCSPCert.Equal(prev.ocspCert)) {
+		if err := h.Cache.Flush(); err != nil {
+			log.Printf("[ERROR]: could not flush the response cache: %v", err)
+		} else {
+			log.Println("[INFO]: the signer has changed, the response cache has been flushed")
+		}
+	}
+	h.SetOptions(w.HandlerOptions)
+	w.WebServer.Reroute()
+	closeToken(prevCloser)
+
+	log.Println("[INFO]: the configuration has been reloaded")
+	return nil
+}
+
+// ReloadOnSignal reloads the configuration every time a signal is received
+// on signals, errors are logged as there's nobody to report them to
+func (w *Worker) ReloadOnSignal(signals <-chan os.Signal) {
+	go func() {
+		for sig := range signals {
+			log.Printf("[INFO]: received %v, reloading the configuration", sig)
+			if err := w.Reload(); err != nil {
+				log.Printf("[ERROR]: could not reload the configuration, the previous one is kept: %v", err)
+			}
+		}
+	}()
+}
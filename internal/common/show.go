@@ -0,0 +1,64 @@
+package common
+
+import (
+	"time"
+)
+
+// SettingValue is the effective value of a setting and where it comes from
+type SettingValue struct {
+	Name    string `json:"name"`
+	Section string `json:"section"`
+	Key     string `json:"key"`
+	Value   any    `json:"value"`
+	Source  string `json:"source"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Effective returns the value of every setting, with the database passwords
+// and every other secret redacted
+func (s *Settings) Effective() []SettingValue {
+	values := []SettingValue{}
+
+	for _, setting := range settings {
+		v, source, err := s.resolve(setting.Name)
+		if setting.Name == "dburl" && err == nil {
+			v, source, err = s.databaseURL()
+		}
+
+		value := SettingValue{
+			Name:    setting.Name,
+			Section: setting.Section,
+			Key:     setting.Key,
+			Value:   redactValue(setting, v),
+			Source:  source,
+		}
+		if err != nil {
+			value.Error = err.Error()
+		}
+		values = append(values, value)
+	}
+
+	return values
+}
+
+// redactValue applies the redaction of the setting, durations are shown as
+// strings instead of nanoseconds
+func redactValue(setting Setting, v any) any {
+	switch v := v.(type) {
+	case string:
+		if setting.Redact != nil {
+			return setting.Redact(v)
+		}
+	case []string:
+		if setting.Redact != nil {
+			redacted := []string{}
+			for _, item := range v {
+				redacted = append(redacted, setting.Redact(item))
+			}
+			return redacted
+		}
+	case time.Duration:
+		return v.String()
+	}
+	return v
+}
@@ -0,0 +1,55 @@
+package common
+
+import (
+	"crypto"
+	"io"
+	"log"
+
+	"github.com/open-uem/openuem-ocsp-responder/internal/signer"
+)
+
+// loadSigner sets the OCSP signing key, read from a PKCS#11 token if one is
+// configured or from the PEM key otherwise, set inline or in a file, which may
+// be encrypted
+func (w *Worker) loadSigner() error {
+	w.closeSigner()
+
+	if w.PKCS11.Enabled() {
+		key, closer, err := signer.NewPKCS11Signer(w.PKCS11, w.OCSPCert)
+		if err != nil {
+			return err
+		}
+		w.OCSPPrivateKey = key
+		w.signerCloser = closer
+		return nil
+	}
+
+	var key crypto.Signer
+	var err error
+	if w.OCSPKeyPEM != "" {
+		key, err = signer.ParsePrivateKey([]byte(w.OCSPKeyPEM), w.OCSPKeyPassphrase)
+	} else {
+		key, err = signer.ReadPrivateKey(w.OCSPKeyPath, w.OCSPKeyPassphrase)
+	}
+	if err != nil {
+		return err
+	}
+	if err := signer.CheckPublicKey(key, w.OCSPCert); err != nil {
+		return err
+	}
+	w.OCSPPrivateKey = key
+	return nil
+}
+
+func (w *Worker) closeSigner() {
+	closeToken(w.signerCloser)
+	w.signerCloser = nil
+}
+
+func closeToken(closer io.Closer) {
+	if closer != nil {
+		if err := closer.Close(); err != nil {
+			log.Printf("[ERROR]: could not close the PKCS#11 token, reason: %v", err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/open-uem/openuem-ocsp-responder/internal/metrics"
+	"github.com/open-uem/openuem-ocsp-responder/internal/models"
+)
+
+// Status is the state of the running responder reported by the admin API
+type Status struct {
+	PID         int                    `json:"pid"`
+	StartedAt   time.Time              `json:"started_at"`
+	Uptime      string                 `json:"uptime"`
+	Listen      []string               `json:"listen"`
+	AdminListen []string               `json:"admin_listen"`
+	Signer      *SignerStatus          `json:"signer,omitempty"`
+	Ready       bool                   `json:"ready"`
+	NotReady    string                 `json:"not_ready,omitempty"`
+	Database    []models.BackendStatus `json:"database"`
+	// Connection and Configuration are the state of the attempts to connect
+	// with the database and, for the service, to load the configuration
+	Connection    *RetryState `json:"database_connection,omitempty"`
+	Configuration *RetryState `json:"configuration,omitempty"`
+	// Reconnection is the state of the last reconnection with the database,
+	// if the connection has been lost
+	Reconnection *RetryState       `json:"database_reconnection,omitempty"`
+	Counters     map[string]uint64 `json:"counters"`
+}
+
+// SignerStatus identifies the certificate used to sign responses
+type SignerStatus struct {
+	Subject  string    `json:"subject"`
+	Serial   string    `json:"serial"`
+	NotAfter time.Time `json:"not_after"`
+}
+
+// Status returns the state of the running responder
+func (w *Worker) Status() Status {
+	// The addresses and the web server are changed by a reload
+	w.reloadMu.Lock()
+	webServer := w.WebServer
+	listen, adminListen := w.ListenAddresses(), w.AdminListen
+	if webServer != nil {
+		listen = webServer.Addresses
+	}
+	w.reloadMu.Unlock()
+
+	s := Status{
+		PID:         os.Getpid(),
+		StartedAt:   w.StartedAt,
+		Uptime:      time.Since(w.StartedAt).Truncate(time.Second).String(),
+		Listen:      listen,
+		AdminListen: adminListen,
+		Database:    []models.BackendStatus{},
+		Counters:    metrics.Values(),
+	}
+
+	if webServer != nil {
+		_, ocspCert, _ := webServer.Handler.Signer()
+		if ocspCert != nil {
+			s.Signer = &SignerStatus{
+				Subject:  ocspCert.Subject.String(),
+				Serial:   fmt.Sprintf("%X", ocspCert.SerialNumber),
+				NotAfter: ocspCert.NotAfter,
+			}
+		}
+	}
+
+	if model := w.model(); model != nil {
+		s.Database = model.Status()
+	}
+
+	if err := w.Ready(); err != nil {
+		s.NotReady = err.Error()
+	} else {
+		s.Ready = true
+	}
+	if w.dbConnect != nil {
+		state := w.dbConnect.State()
+		s.Connection = &state
+	}
+	if w.configLoad != nil {
+		state := w.configLoad.State()
+		s.Configuration = &state
+	}
+	if r := w.reconnection(); r != nil {
+		state := r.State()
+		s.Reconnection = &state
+	}
+
+	return s
+}
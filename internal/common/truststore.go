@@ -0,0 +1,74 @@
+package common
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	"github.com/open-uem/openuem-ocsp-responder/internal/server/handler"
+)
+
+// readPEMCertificate returns the first certificate in a PEM file. Unlike
+// utils.ReadPEMCertificate it doesn't panic if the file isn't PEM encoded,
+// which matters as the files can be replaced while the responder runs
+func readPEMCertificate(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := parsePEMCertificate(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return cert, nil
+}
+
+// parsePEMCertificate returns the first certificate in PEM encoded data
+func parsePEMCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no PEM certificate found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// readCertPool returns a pool with every certificate in a PEM file
+func readCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", path, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// setRequestSignaturePolicy sets how signed requests are handled, a trust
+// store is required unless they're ignored
+func (w *Worker) setRequestSignaturePolicy(policy string) error {
+	var err error
+
+	w.HandlerOptions.RequestSignature, err = handler.ParseRequestSignaturePolicy(policy)
+	if err != nil {
+		return err
+	}
+
+	w.HandlerOptions.RequestTrustStore = nil
+	if w.HandlerOptions.RequestSignature == handler.IgnoreSignature {
+		return nil
+	}
+
+	if w.RequestTrustStorePath == "" {
+		return fmt.Errorf("a trust store is required to verify signed requests")
+	}
+	w.HandlerOptions.RequestTrustStore, err = readCertPool(w.RequestTrustStorePath)
+	if err != nil {
+		return fmt.Errorf("could not read the request trust store: %v", err)
+	}
+	return nil
+}
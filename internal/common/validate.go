@@ -0,0 +1,248 @@
+package common
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/open-uem/openuem-ocsp-responder/internal/models"
+	"github.com/open-uem/openuem-ocsp-responder/internal/server"
+)
+
+// Status of a check of the configuration
+const (
+	CheckOK      = "ok"
+	CheckWarning = "warning"
+	CheckFailed  = "error"
+)
+
+// expiryWarning is how long before a certificate expires it's reported by
+// the validation
+const expiryWarning = 30 * 24 * time.Hour
+
+// Check is the result of a check of the configuration
+type Check struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+type validation struct {
+	checks []Check
+}
+
+func (v *validation) add(name, status, format string, args ...any) {
+	v.checks = append(v.checks, Check{Name: name, Status: status, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidateConfig loads the configuration from the settings as the responder
+// would, without connecting with the database or listening, and checks the
+// files, the certificates, the key pair, the database urls and the listen
+// addresses. Unlike the responder it doesn't stop at the first problem
+func ValidateConfig(s *Settings) []Check {
+	v := &validation{}
+	w := NewWorker("")
+	defer w.closeSigner()
+
+	for _, setting := range settings {
+		if _, _, err := s.resolve(setting.Name); err != nil {
+			v.add("--"+setting.Name, CheckFailed, "%v", err)
+		}
+	}
+
+	if err := w.loadDatabaseConfig(s); err != nil {
+		v.add("database", CheckFailed, "%v", err)
+	} else {
+		v.database(w)
+	}
+
+	if err := w.loadCertificates(s); err != nil {
+		v.add("certificates", CheckFailed, "%v", err)
+	} else {
+		v.certificates(w)
+
+		if err := w.loadSigningKey(s); err != nil {
+			v.add("signing key", CheckFailed, "%v", err)
+		} else {
+			v.signingKey(w)
+		}
+	}
+
+	if err := w.loadHTTPConfig(s); err != nil {
+		v.add("OCSP endpoint", CheckFailed, "%v", err)
+	} else {
+		v.listeners("listen address", w.ListenAddresses())
+		v.add("request signatures", CheckOK, "%s", w.HandlerOptions.RequestSignature)
+		v.cache(w.CacheDir)
+	}
+
+	if err := w.loadAdminConfig(s); err != nil {
+		v.add("admin API", CheckFailed, "%v", err)
+	} else {
+		v.admin(w)
+	}
+
+	return v.checks
+}
+
+// Failed reports if any check failed
+func Failed(checks []Check) bool {
+	return slices.ContainsFunc(checks, func(c Check) bool {
+		return c.Status == CheckFailed
+	})
+}
+
+func (v *validation) database(w *Worker) {
+	if err := models.CheckURL(w.DBUrl); err != nil {
+		v.add("database url", CheckFailed, "%v", err)
+	} else {
+		v.add("database url", CheckOK, "%s", redactURL(w.DBUrl))
+	}
+
+	for _, replica := range w.DBReplicaUrls {
+		if err := models.CheckURL(replica); err != nil {
+			v.add("database replica url", CheckFailed, "%v", err)
+		} else {
+			v.add("database replica url", CheckOK, "%s", redactURL(replica))
+		}
+	}
+}
+
+func (v *validation) certificates(w *Worker) {
+	v.validity("CA certificate", w.CACert)
+
+	if !v.validity("OCSP certificate", w.OCSPCert) || w.OCSPCert.Equal(w.CACert) {
+		return
+	}
+
+	// A delegated responder must be issued by the CA and be authorized to
+	// sign responses, see RFC 6960 section 4.2.2.2
+	if err := w.OCSPCert.CheckSignatureFrom(w.CACert); err != nil {
+		v.add("OCSP certificate", CheckFailed, "the certificate is not issued by the CA: %v", err)
+	}
+	if !slices.Contains(w.OCSPCert.ExtKeyUsage, x509.ExtKeyUsageOCSPSigning) {
+		v.add("OCSP certificate", CheckFailed, "the certificate doesn't have the OCSP Signing extended key usage, clients will reject the responses")
+	}
+}
+
+// validity checks the validity period of a certificate, it returns false if
+// the certificate can't be used
+func (v *validation) validity(name string, cert *x509.Certificate) bool {
+	now := time.Now()
+
+	switch {
+	case now.Before(cert.NotBefore):
+		v.add(name, CheckFailed, "%s is not valid until %s", cert.Subject, cert.NotBefore.Format(time.RFC3339))
+		return false
+	case now.After(cert.NotAfter):
+		v.add(name, CheckFailed, "%s expired on %s", cert.Subject, cert.NotAfter.Format(time.RFC3339))
+		return false
+	case now.Add(expiryWarning).After(cert.NotAfter):
+		v.add(name, CheckWarning, "%s expires on %s", cert.Subject, cert.NotAfter.Format(time.RFC3339))
+	default:
+		v.add(name, CheckOK, "%s, valid until %s", cert.Subject, cert.NotAfter.Format(time.RFC3339))
+	}
+	return true
+}
+
+func (v *validation) signingKey(w *Worker) {
+	source := w.OCSPKeyPath
+	switch {
+	case w.PKCS11.Enabled():
+		source = "the PKCS#11 token"
+	case w.OCSPKeyPEM != "":
+		source = "--key-pem"
+	}
+
+	algorithm := "chosen from the key type"
+	if w.ResponseOptions.SignatureAlgorithm != x509.UnknownSignatureAlgorithm {
+		algorithm = w.ResponseOptions.SignatureAlgorithm.String()
+	}
+
+	v.add("signing key", CheckOK, "the key in %s matches the OCSP certificate, signature algorithm %s", source, algorithm)
+}
+
+// listeners checks that the addresses are valid and can be bound. An address
+// in use is only a warning, as it may be the running responder. Unix domain
+// sockets aren't bound, as that would remove the socket of the responder
+func (v *validation) listeners(name string, addresses []string) {
+	for _, address := range addresses {
+		network, addr := server.ParseAddress(address)
+
+		if network == "unix" {
+			dir := filepath.Dir(addr)
+			if info, err := os.Stat(dir); !isAbstractSocket(addr) && (err != nil || !info.IsDir()) {
+				v.add(name, CheckFailed, "%s: the directory %s doesn't exist", address, dir)
+			} else {
+				v.add(name, CheckOK, "%s", address)
+			}
+			continue
+		}
+
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			v.add(name, CheckFailed, "%s: %v", address, err)
+			continue
+		}
+		if number, err := net.LookupPort("tcp", port); err != nil || number == 0 {
+			v.add(name, CheckFailed, "%s: invalid port %q", address, port)
+			continue
+		}
+
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			v.add(name, CheckWarning, "%s can't be bound, the responder may be running: %v", address, err)
+			continue
+		}
+		l.Close()
+		v.add(name, CheckOK, "%s", address)
+	}
+}
+
+func isAbstractSocket(addr string) bool {
+	return len(addr) > 0 && addr[0] == '@'
+}
+
+func (v *validation) cache(dir string) {
+	if dir == "" {
+		v.add("cache directory", CheckOK, "responses are cached in memory only")
+		return
+	}
+
+	info, err := os.Stat(dir)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		v.add("cache directory", CheckWarning, "%s doesn't exist, it will be created", dir)
+	case err != nil:
+		v.add("cache directory", CheckFailed, "%v", err)
+	case !info.IsDir():
+		v.add("cache directory", CheckFailed, "%s is not a directory", dir)
+	default:
+		v.add("cache directory", CheckOK, "%s", dir)
+	}
+}
+
+func (v *validation) admin(w *Worker) {
+	if len(w.AdminListen) == 0 {
+		v.add("admin API", CheckOK, "disabled")
+		return
+	}
+
+	tlsConfig, err := w.adminTLSConfig()
+	if err != nil {
+		v.add("admin API", CheckFailed, "%v", err)
+		return
+	}
+	if _, err := server.NewAdmin(nil, w.AdminListen, tlsConfig); err != nil {
+		v.add("admin API", CheckFailed, "%v", err)
+		return
+	}
+
+	v.listeners("admin listen address", w.AdminListen)
+}
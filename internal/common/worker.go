@@ -1,32 +1,75 @@
 package common
 
 import (
-	"crypto/rsa"
+	"crypto"
 	"crypto/x509"
+	"errors"
+	"io"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/go-co-op/gocron/v2"
+	"github.com/open-uem/openuem-ocsp-responder/internal/cache"
 	"github.com/open-uem/openuem-ocsp-responder/internal/models"
 	"github.com/open-uem/openuem-ocsp-responder/internal/server"
+	"github.com/open-uem/openuem-ocsp-responder/internal/server/handler"
+	"github.com/open-uem/openuem-ocsp-responder/internal/signer"
 	"github.com/open-uem/utils"
 )
 
 type Worker struct {
-	Model          *models.Model
-	WebServer      *server.WebServer
-	Logger         *utils.OpenUEMLogger
-	DBConnectJob   gocron.Job
-	ConfigJob      gocron.Job
-	TaskScheduler  gocron.Scheduler
-	DBUrl          string
-	CACert         *x509.Certificate
-	OCSPCert       *x509.Certificate
-	OCSPPrivateKey *rsa.PrivateKey
-	Port           string
+	Model                 *models.Model
+	WebServer             *server.WebServer
+	AdminServer           *server.AdminServer
+	Logger                *utils.OpenUEMLogger
+	TaskScheduler         gocron.Scheduler
+	DBUrl                 string
+	DBReplicaUrls         []string
+	DBOptions             models.Options
+	DBRetry               Backoff
+	DBProbeInterval       time.Duration
+	dbConnect             *retrier
+	dbMu                  sync.Mutex
+	dbReconnect           *retrier
+	configLoad            *retrier
+	failed                chan error
+	LoadConfig            func() error
+	ConfigFile            string
+	CACertPath            string
+	CACert                *x509.Certificate
+	OCSPCertPath          string
+	OCSPCert              *x509.Certificate
+	OCSPKeyPath           string
+	OCSPKeyPEM            string
+	OCSPPrivateKey        crypto.Signer
+	OCSPKeyPassphrase     string
+	PKCS11                signer.PKCS11Config
+	signerCloser          io.Closer
+	ResponseOptions       signer.ResponseOptions
+	Port                  string
+	Listen                []string
+	CacheDir              string
+	CacheMaxEntries       int
+	Cache                 *cache.Cache
+	HandlerOptions        handler.Options
+	RequestTrustStorePath string
+	AdminListen           []string
+	AdminCertPath         string
+	AdminKeyPath          string
+	AdminClientCAPath     string
+	StartedAt             time.Time
+	reloadMu              sync.Mutex
+	reloading             sync.Mutex
 }
 
 func NewWorker(logName string) *Worker {
-	worker := Worker{}
+	worker := Worker{
+		DBOptions:      models.DefaultOptions(),
+		DBRetry:        DefaultBackoff(),
+		HandlerOptions: handler.DefaultOptions(),
+		failed:         make(chan error, 1),
+	}
 	if logName != "" {
 		worker.Logger = utils.NewLogger(logName)
 	}
@@ -35,31 +78,99 @@ func NewWorker(logName string) *Worker {
 }
 
 func (w *Worker) StartWorker() {
-	// Start a job to try to connect with the database
-	if err := w.StartDBConnectJob(); err != nil {
-		log.Printf("[ERROR]: could not start DB connect job, reason: %s", err.Error())
-		return
+	w.StartedAt = time.Now()
+
+	// The listeners are started right away, requests are answered from the
+	// cache or with tryLater until the database is connected
+	w.dbConnect = &retrier{
+		operation: "connect with the database",
+		attempt:   w.connectDatabase,
+		backoff:   w.retryBackoff,
+		scheduler: w.TaskScheduler,
+		onGiveUp:  w.fail,
 	}
+	w.StartOCSPResponderWebService()
+
+	w.StartDBConnectJob()
+	w.StartDBProbeJob()
+	w.StartCachePruneJob()
 }
 
 func (w *Worker) StopWorker() {
-	if w.Model != nil {
-		w.Model.Close()
-	}
-
+	// The jobs are stopped first so the database isn't probed once closed
 	if w.TaskScheduler != nil {
 		if err := w.TaskScheduler.Shutdown(); err != nil {
 			log.Printf("[ERROR]: could not stop the task scheduler, reason: %s", err.Error())
 		}
 	}
 
+	if model := w.model(); model != nil {
+		model.Close()
+	}
+
 	if w.WebServer != nil {
 		w.WebServer.Close()
 	}
 
+	if w.AdminServer != nil {
+		w.AdminServer.Close()
+	}
+
+	w.closeSigner()
+
 	log.Println("[INFO]: the OCSP responder has stopped")
 	if w.Logger != nil {
 		w.Logger.Close()
 	}
 
 }
+
+// Ready returns why the responder can't answer requests, it's nil once the
+// configuration is loaded and the database is connected, as long as the
+// connection isn't lost
+func (w *Worker) Ready() error {
+	if w.configLoad != nil {
+		if err := w.configLoad.State().Err(w.configLoad.operation); err != nil {
+			return err
+		}
+	}
+	if w.dbConnect == nil {
+		return errors.New("the responder hasn't been started")
+	}
+	if err := w.dbConnect.State().Err(w.dbConnect.operation); err != nil {
+		return err
+	}
+	if r := w.reconnection(); r != nil {
+		return r.State().Err(r.operation)
+	}
+	return nil
+}
+
+// Failed returns a channel that receives an error if the responder gives up
+// connecting with the database or loading the configuration, or if it can't
+// listen on its addresses or set up the admin API
+func (w *Worker) Failed() <-chan error {
+	return w.failed
+}
+
+func (w *Worker) fail(err error) {
+	select {
+	case w.failed <- err:
+	default:
+	}
+}
+
+// retryBackoff returns the backoff of the retries, which can be changed by a
+// reload
+func (w *Worker) retryBackoff() Backoff {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+	return w.DBRetry
+}
+
+// model returns the database model, which is set by the connect job
+func (w *Worker) model() *models.Model {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+	return w.Model
+}
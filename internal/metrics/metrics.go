@@ -0,0 +1,184 @@
+package metrics
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Counter is a monotonically increasing value exported in the Prometheus
+// text format
+type Counter struct {
+	name  string
+	help  string
+	value atomic.Uint64
+}
+
+// Gauge is a value that can go up and down exported in the Prometheus text
+// format
+type Gauge struct {
+	name  string
+	help  string
+	value atomic.Int64
+}
+
+// CounterVec is a counter partitioned by the value of a single label
+type CounterVec struct {
+	name   string
+	help   string
+	label  string
+	mu     sync.Mutex
+	values map[string]*atomic.Uint64
+}
+
+var (
+	mu          sync.Mutex
+	counters    []*Counter
+	counterVecs []*CounterVec
+	gauges      []*Gauge
+)
+
+var (
+	Requests              = NewCounter("openuem_ocsp_requests_total", "OCSP requests received")
+	Responses             = NewCounter("openuem_ocsp_responses_total", "Responses signed with the current revocation status")
+	StaleResponses        = NewCounter("openuem_ocsp_stale_responses_total", "Signed responses served from the last known good cache while the database was unavailable")
+	TryLater              = NewCounter("openuem_ocsp_try_later_total", "Requests answered with the tryLater status")
+	Throttled             = NewCounter("openuem_ocsp_throttled_total", "Requests rejected by the rate limits or because too many responses were being signed")
+	SignatureRequired     = NewCounter("openuem_ocsp_signature_required_total", "Requests rejected with the sigRequired status because they weren't signed by a trusted client")
+	DatabaseProbeFailures = NewCounter("openuem_ocsp_database_probe_failures_total", "Liveness probes of the database that failed")
+	DatabaseLost          = NewCounter("openuem_ocsp_database_connection_lost_total", "Times the connection with the database was lost after startup")
+	DatabaseReconnects    = NewCounter("openuem_ocsp_database_reconnects_total", "Times the connection with the database was established again after being lost")
+)
+
+var BackendLookups = NewCounterVec("openuem_ocsp_backend_lookups_total", "Revocation lookups answered by each database", "backend")
+
+var DatabaseUp = NewGauge("openuem_ocsp_database_up", "Whether the database can serve revocation lookups (1) or not (0)")
+
+// NewCounter creates and registers a counter
+func NewCounter(name, help string) *Counter {
+	c := &Counter{name: name, help: help}
+
+	mu.Lock()
+	defer mu.Unlock()
+	counters = append(counters, c)
+
+	return c
+}
+
+// NewCounterVec creates and registers a counter partitioned by label
+func NewCounterVec(name, help, label string) *CounterVec {
+	v := &CounterVec{name: name, help: help, label: label, values: map[string]*atomic.Uint64{}}
+
+	mu.Lock()
+	defer mu.Unlock()
+	counterVecs = append(counterVecs, v)
+
+	return v
+}
+
+// NewGauge creates and registers a gauge
+func NewGauge(name, help string) *Gauge {
+	g := &Gauge{name: name, help: help}
+
+	mu.Lock()
+	defer mu.Unlock()
+	gauges = append(gauges, g)
+
+	return g
+}
+
+func (g *Gauge) Set(value int64) {
+	g.value.Store(value)
+}
+
+func (g *Gauge) Value() int64 {
+	return g.value.Load()
+}
+
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+// Inc increments the counter of the given label value
+func (v *CounterVec) Inc(value string) {
+	v.mu.Lock()
+	counter, ok := v.values[value]
+	if !ok {
+		counter = &atomic.Uint64{}
+		v.values[value] = counter
+	}
+	v.mu.Unlock()
+
+	counter.Add(1)
+}
+
+// series returns the name of the series of every label value, sorted, and
+// its value
+func (v *CounterVec) series() ([]string, map[string]uint64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	names := []string{}
+	values := map[string]uint64{}
+	for value, counter := range v.values {
+		name := fmt.Sprintf("%s{%s=%q}", v.name, v.label, value)
+		names = append(names, name)
+		values[name] = counter.Load()
+	}
+	slices.Sort(names)
+	return names, values
+}
+
+// Values returns the value of every registered counter by name
+func Values() map[string]uint64 {
+	mu.Lock()
+	defer mu.Unlock()
+
+	values := map[string]uint64{}
+	for _, counter := range counters {
+		values[counter.name] = counter.Value()
+	}
+	for _, v := range counterVecs {
+		_, series := v.series()
+		maps.Copy(values, series)
+	}
+	return values
+}
+
+// Handler writes every registered metric in the Prometheus text format
+func Handler(c echo.Context) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var sb strings.Builder
+	for _, counter := range counters {
+		fmt.Fprintf(&sb, "# HELP %s %s\n", counter.name, counter.help)
+		fmt.Fprintf(&sb, "# TYPE %s counter\n", counter.name)
+		fmt.Fprintf(&sb, "%s %d\n", counter.name, counter.Value())
+	}
+	for _, v := range counterVecs {
+		fmt.Fprintf(&sb, "# HELP %s %s\n", v.name, v.help)
+		fmt.Fprintf(&sb, "# TYPE %s counter\n", v.name)
+		names, series := v.series()
+		for _, name := range names {
+			fmt.Fprintf(&sb, "%s %d\n", name, series[name])
+		}
+	}
+	for _, gauge := range gauges {
+		fmt.Fprintf(&sb, "# HELP %s %s\n", gauge.name, gauge.help)
+		fmt.Fprintf(&sb, "# TYPE %s gauge\n", gauge.name)
+		fmt.Fprintf(&sb, "%s %d\n", gauge.name, gauge.Value())
+	}
+
+	return c.Blob(http.StatusOK, "text/plain; version=0.0.4", []byte(sb.String()))
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	entsql "entgo.io/ent/dialect/sql"
+	ent "github.com/open-uem/ent"
+	"github.com/open-uem/ent/revocation"
+)
+
+// backendRetryInterval is the time a failed backend is skipped before
+// lookups are routed to it again
+const backendRetryInterval = 30 * time.Second
+
+// backend is one of the databases that can serve revocation lookups
+type backend struct {
+	Name   string
+	Client *ent.Client
+	DB     *sql.DB
+
+	mu        sync.Mutex
+	downUntil time.Time
+	lastError error
+}
+
+func openBackend(name string, dbUrl string, opts Options) (*backend, error) {
+	driverName, dialectName, dsn, err := openParams(dbUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect with %s database: %v", dialectName, err)
+	}
+
+	if opts.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	if opts.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
+
+	return &backend{
+		Name:   name,
+		DB:     db,
+		Client: ent.NewClient(ent.Driver(entsql.OpenDB(dialectName, db))),
+	}, nil
+}
+
+// checkSchema verifies that the revocations table and the columns used by
+// the responder exist
+func (b *backend) checkSchema(ctx context.Context) error {
+	if err := b.DB.PingContext(ctx); err != nil {
+		return fmt.Errorf("could not connect with database %s: %v", b.Name, err)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE 1 = 0", strings.Join(revocation.Columns, ", "), revocation.Table)
+	rows, err := b.DB.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("%w, table %s with columns %s is required in database %s (run the migrate command if the database is new): %v", ErrSchemaMismatch, revocation.Table, strings.Join(revocation.Columns, ", "), b.Name, err)
+	}
+	return rows.Close()
+}
+
+func (b *backend) isHealthy(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !now.Before(b.downUntil)
+}
+
+func (b *backend) markDown(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.downUntil = time.Now().Add(backendRetryInterval)
+	b.lastError = err
+}
+
+func (b *backend) markUp() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.downUntil = time.Time{}
+	b.lastError = nil
+}
+
+// status returns the health of the backend
+func (b *backend) status(now time.Time) BackendStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := BackendStatus{
+		Name:    b.Name,
+		Healthy: !now.Before(b.downUntil),
+	}
+	if !s.Healthy {
+		retryAt := b.downUntil
+		s.RetryAt = &retryAt
+	}
+	if b.lastError != nil {
+		s.LastError = b.lastError.Error()
+	}
+	return s
+}
+
+// failed reports if err means that the backend couldn't answer, in which case
+// the lookup must be retried in another backend
+func failed(err error) bool {
+	return err != nil && !ent.IsNotFound(err) && !errors.Is(err, context.Canceled)
+}
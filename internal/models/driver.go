@@ -0,0 +1,95 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"entgo.io/ent/dialect"
+	"github.com/jackc/pgx/v5"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// openParams returns the database/sql driver, the ent dialect and the DSN
+// that must be used for the given database url. The backend is selected
+// using the url scheme: postgres:// and postgresql:// use Postgres while
+// sqlite:// and file: use a local SQLite database file. A Postgres keyword
+// DSN, such as host=localhost user=openuem, has no scheme and is passed to
+// pgx as is
+func openParams(dbUrl string) (driverName string, dialectName string, dsn string, err error) {
+	if urlScheme(dbUrl) == "" {
+		if _, err := pgx.ParseConfig(dbUrl); err != nil {
+			return "", "", "", fmt.Errorf("could not parse database url: %v", err)
+		}
+		return "pgx", dialect.Postgres, dbUrl, nil
+	}
+
+	u, err := url.Parse(dbUrl)
+	if err != nil {
+		return "", "", "", fmt.Errorf("could not parse database url: %v", err)
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "postgres", "postgresql":
+		return "pgx", dialect.Postgres, dbUrl, nil
+	case "sqlite", "sqlite3", "file":
+		dsn, err := sqliteDSN(u)
+		if err != nil {
+			return "", "", "", err
+		}
+		return "sqlite", dialect.SQLite, dsn, nil
+	default:
+		return "", "", "", fmt.Errorf("unsupported database url scheme %q", u.Scheme)
+	}
+}
+
+// urlScheme returns the scheme of a database url, as defined by RFC 3986, or
+// an empty string if there's none
+func urlScheme(dbUrl string) string {
+	i := strings.Index(dbUrl, ":")
+	if i <= 0 {
+		return ""
+	}
+	for j, r := range dbUrl[:i] {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z':
+		case j > 0 && ('0' <= r && r <= '9' || r == '+' || r == '-' || r == '.'):
+		default:
+			return ""
+		}
+	}
+	return strings.ToLower(dbUrl[:i])
+}
+
+// CheckURL reports if a database url is valid and its backend supported,
+// without connecting with the database
+func CheckURL(dbUrl string) error {
+	_, _, _, err := openParams(dbUrl)
+	return err
+}
+
+// sqliteDSN converts a sqlite://path or file:path url into the DSN expected
+// by the pure-Go SQLite driver, enabling foreign keys as required by ent
+func sqliteDSN(u *url.URL) (string, error) {
+	path := u.Opaque
+	if path == "" {
+		path = u.Host + u.Path
+	}
+	if path == "" {
+		return "", fmt.Errorf("the SQLite database url must contain a file path")
+	}
+
+	query := u.Query()
+	hasForeignKeys := false
+	for _, p := range query["_pragma"] {
+		if strings.HasPrefix(strings.ToLower(p), "foreign_keys") {
+			hasForeignKeys = true
+		}
+	}
+	if !hasForeignKeys {
+		query.Add("_pragma", "foreign_keys(1)")
+	}
+
+	return fmt.Sprintf("file:%s?%s", path, query.Encode()), nil
+}
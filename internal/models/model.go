@@ -2,41 +2,198 @@ package models
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"fmt"
-	"os"
+	"log"
+	"sync/atomic"
+	"time"
 
-	"entgo.io/ent/dialect"
-	entsql "entgo.io/ent/dialect/sql"
-	_ "github.com/jackc/pgx/v5/stdlib"
 	ent "github.com/open-uem/ent"
 )
 
+// ErrSchemaMismatch is returned when the database doesn't contain the tables
+// and columns expected by this version of the responder
+var ErrSchemaMismatch = errors.New("the database schema doesn't match the one expected by the OCSP responder")
+
+// Model gives access to the primary database and to its read replicas.
+// Client always belongs to the primary
 type Model struct {
-	Client *ent.Client
+	Client   *ent.Client
+	Options  Options
+	primary  *backend
+	replicas []*backend
+	next     atomic.Uint64
+}
+
+// Options holds the connection pool settings and the timeout applied to
+// every revocation lookup. Zero values keep the database/sql defaults
+type Options struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	QueryTimeout    time.Duration
+}
+
+// DefaultOptions returns the pool settings used when none are configured
+func DefaultOptions() Options {
+	return Options{
+		MaxOpenConns:    10,
+		MaxIdleConns:    5,
+		ConnMaxLifetime: 30 * time.Minute,
+		QueryTimeout:    5 * time.Second,
+	}
 }
 
-func New(dbUrl string) (*Model, error) {
-	model := Model{}
+// New opens a read-only model using the primary database url and optional
+// read replicas. The database schema is never modified, it's only checked
+// so a version mismatch is reported at startup. Unreachable databases are
+// skipped until they recover, but at least one of them must be available
+func New(dbUrl string, replicaUrls []string, opts Options) (*Model, error) {
+	var err error
 
-	db, err := sql.Open("pgx", dbUrl)
+	model := Model{Options: opts}
+
+	model.primary, err = openBackend("primary", dbUrl, opts)
 	if err != nil {
-		return nil, fmt.Errorf("could not connect with Postgres database: %v", err)
+		return nil, err
+	}
+	model.Client = model.primary.Client
+
+	for i, replicaUrl := range replicaUrls {
+		replica, err := openBackend(fmt.Sprintf("replica-%d", i+1), replicaUrl, opts)
+		if err != nil {
+			model.Close()
+			return nil, err
+		}
+		model.replicas = append(model.replicas, replica)
 	}
 
-	model.Client = ent.NewClient(ent.Driver(entsql.OpenDB(dialect.Postgres, db)))
+	available := 0
+	var lastErr error
+	for _, b := range model.backends() {
+		ctx, cancel := model.queryContext(context.Background())
+		err := b.checkSchema(ctx)
+		cancel()
 
-	// TODO Automatic migrations only in development
-	ctx := context.Background()
-	if os.Getenv("ENV") != "prod" {
-		if err := model.Client.Schema.Create(ctx); err != nil {
+		if errors.Is(err, ErrSchemaMismatch) {
+			model.Close()
 			return nil, err
 		}
+		if err != nil {
+			log.Printf("[WARN]: %v", err)
+			b.markDown(err)
+			lastErr = err
+			continue
+		}
+		available++
+	}
+
+	if available == 0 {
+		model.Close()
+		return nil, lastErr
 	}
 
 	return &model, nil
 }
 
+// Migrate creates or updates the database schema. It's only used by the
+// migrate command as the responder must not alter the shared OpenUEM schema
+func Migrate(dbUrl string) error {
+	b, err := openBackend("primary", dbUrl, Options{})
+	if err != nil {
+		return err
+	}
+	defer b.Client.Close()
+
+	if err := b.Client.Schema.Create(context.Background()); err != nil {
+		return fmt.Errorf("could not migrate the database schema: %v", err)
+	}
+
+	return nil
+}
+
+// BackendStatus is the health of one of the databases
+type BackendStatus struct {
+	Name      string     `json:"name"`
+	Healthy   bool       `json:"healthy"`
+	LastError string     `json:"last_error,omitempty"`
+	RetryAt   *time.Time `json:"retry_at,omitempty"`
+}
+
+// Status returns the health of the primary database and its replicas
+func (m *Model) Status() []BackendStatus {
+	now := time.Now()
+	status := []BackendStatus{}
+	for _, b := range m.backends() {
+		status = append(status, b.status(now))
+	}
+	return status
+}
+
+// Probe checks every database with the query used at startup, marking the
+// ones that fail as down and the ones that answer as healthy again. It fails
+// if none of them can serve lookups
+func (m *Model) Probe(ctx context.Context) error {
+	available := 0
+	var lastErr error
+	for _, b := range m.backends() {
+		queryCtx, cancel := m.queryContext(ctx)
+		err := b.checkSchema(queryCtx)
+		cancel()
+
+		if err != nil {
+			b.markDown(err)
+			lastErr = err
+			continue
+		}
+		b.markUp()
+		available++
+	}
+
+	if available == 0 {
+		return lastErr
+	}
+	return nil
+}
+
+// backends returns every backend, the primary first
+func (m *Model) backends() []*backend {
+	return append([]*backend{m.primary}, m.replicas...)
+}
+
+// lookupOrder returns the backends in the order they must be queried:
+// healthy replicas in round robin and then the primary, which is always
+// tried as the last resort
+func (m *Model) lookupOrder() []*backend {
+	now := time.Now()
+	order := []*backend{}
+
+	if len(m.replicas) > 0 {
+		start := int(m.next.Add(1) % uint64(len(m.replicas)))
+		for i := range m.replicas {
+			replica := m.replicas[(start+i)%len(m.replicas)]
+			if replica.isHealthy(now) {
+				order = append(order, replica)
+			}
+		}
+	}
+
+	return append(order, m.primary)
+}
+
+// queryContext derives a context from ctx that expires after the configured
+// query timeout so a stalled database doesn't block requests forever
+func (m *Model) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if m.Options.QueryTimeout > 0 {
+		return context.WithTimeout(ctx, m.Options.QueryTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
 func (m *Model) Close() {
-	m.Client.Close()
+	for _, b := range m.backends() {
+		if b != nil {
+			b.Client.Close()
+		}
+	}
 }
@@ -2,11 +2,87 @@ package models
 
 import (
 	"context"
+	"log"
 
 	openuem_ent "github.com/open-uem/ent"
 	"github.com/open-uem/ent/revocation"
 )
 
-func (m *Model) GetRevoked(serial int64) (*openuem_ent.Revocation, error) {
-	return m.Client.Revocation.Query().Where(revocation.ID(serial)).Only(context.Background())
+// GetRevoked looks up the revocation for serial, failing over to the next
+// backend when one of them can't answer. It also returns the name of the
+// backend that served the lookup
+func (m *Model) GetRevoked(ctx context.Context, serial int64) (*openuem_ent.Revocation, string, error) {
+	var err error
+	var revoked *openuem_ent.Revocation
+
+	for _, b := range m.lookupOrder() {
+		revoked, err = m.getRevoked(ctx, b, serial)
+		if !failed(err) {
+			b.markUp()
+			return revoked, b.Name, err
+		}
+
+		// the request has gone, there's no point in trying other backends
+		if ctx.Err() != nil {
+			return nil, b.Name, err
+		}
+
+		log.Printf("[WARN]: revocation lookup failed in database %s, reason: %v", b.Name, err)
+		b.markDown(err)
+	}
+
+	return nil, "", err
+}
+
+func (m *Model) getRevoked(ctx context.Context, b *backend, serial int64) (*openuem_ent.Revocation, error) {
+	ctx, cancel := m.queryContext(ctx)
+	defer cancel()
+
+	return b.Client.Revocation.Query().Where(revocation.ID(serial)).Only(ctx)
+}
+
+// GetKnownSerials returns the serial numbers of every certificate issued by
+// OpenUEM and of every revoked certificate
+func (m *Model) GetKnownSerials(ctx context.Context) ([]int64, error) {
+	var err error
+
+	for _, b := range m.lookupOrder() {
+		var serials []int64
+		serials, err = m.getKnownSerials(ctx, b)
+		if err == nil {
+			return serials, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		log.Printf("[WARN]: could not get known serials from database %s, reason: %v", b.Name, err)
+	}
+
+	return nil, err
+}
+
+func (m *Model) getKnownSerials(ctx context.Context, b *backend) ([]int64, error) {
+	ctx, cancel := m.queryContext(ctx)
+	defer cancel()
+
+	issued, err := b.Client.Certificate.Query().IDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := b.Client.Revocation.Query().IDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[int64]bool{}
+	serials := []int64{}
+	for _, serial := range append(issued, revoked...) {
+		if !seen[serial] {
+			seen[serial] = true
+			serials = append(serials, serial)
+		}
+	}
+
+	return serials, nil
 }
@@ -0,0 +1,21 @@
+package pidfile
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// The lock is taken on a byte far beyond the end of the file, Windows locks
+// are mandatory and locking the pid would prevent other processes reading it
+const lockOffsetHigh = 1
+
+func lock(f *os.File) error {
+	overlapped := windows.Overlapped{OffsetHigh: lockOffsetHigh}
+	return windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &overlapped)
+}
+
+func unlock(f *os.File) {
+	overlapped := windows.Overlapped{OffsetHigh: lockOffsetHigh}
+	windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, &overlapped)
+}
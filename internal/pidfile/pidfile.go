@@ -0,0 +1,162 @@
+package pidfile
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrLocked is returned by Acquire if another instance holds the PID file
+var ErrLocked = errors.New("the PID file is locked by another instance")
+
+// File is a PID file locked by the running instance. The lock is released by
+// the operating system when the process exits, so a PID file that isn't
+// locked is always stale
+type File struct {
+	path string
+	f    *os.File
+}
+
+// Acquire creates the PID file, locks it and writes the pid of the current
+// process. It fails with ErrLocked if another instance is running, a stale
+// PID file left by a crashed instance is replaced
+func Acquire(path string) (*File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("could not create the directory of the PID file: %v", err)
+	}
+
+	f, err := openLocked(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if pid, err := Read(path); err == nil && pid != os.Getpid() {
+		log.Printf("[WARN]: replacing the stale PID file %s of process %d", path, pid)
+	}
+
+	if err := f.Truncate(0); err != nil {
+		unlock(f)
+		f.Close()
+		return nil, fmt.Errorf("could not write the PID file: %v", err)
+	}
+	if _, err := f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0); err != nil {
+		unlock(f)
+		f.Close()
+		return nil, fmt.Errorf("could not write the PID file: %v", err)
+	}
+	if err := f.Sync(); err != nil {
+		log.Printf("[WARN]: could not sync the PID file: %v", err)
+	}
+
+	return &File{path: path, f: f}, nil
+}
+
+// openLocked opens and locks the PID file. The instance that held it removes
+// the file while locked, so the lock may be taken on a file that has been
+// removed or replaced in between, in which case it's opened again
+func openLocked(path string) (*os.File, error) {
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("could not open the PID file: %v", err)
+		}
+
+		if err := lock(f); err != nil {
+			f.Close()
+			if pid, err := Read(path); err == nil {
+				return nil, fmt.Errorf("%w (pid %d)", ErrLocked, pid)
+			}
+			return nil, ErrLocked
+		}
+
+		opened, err := f.Stat()
+		if err != nil {
+			unlock(f)
+			f.Close()
+			return nil, fmt.Errorf("could not open the PID file: %v", err)
+		}
+		current, err := os.Stat(path)
+		if err == nil && os.SameFile(opened, current) {
+			return f, nil
+		}
+
+		unlock(f)
+		f.Close()
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("could not open the PID file: %v", err)
+		}
+	}
+}
+
+// Release removes the PID file and releases the lock
+func (p *File) Release() {
+	if p == nil || p.f == nil {
+		return
+	}
+
+	// The file is removed while locked so no other instance can acquire it
+	// in between, Windows may refuse to remove an open file so it's retried
+	err := os.Remove(p.path)
+	unlock(p.f)
+	p.f.Close()
+	p.f = nil
+
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		if err := os.Remove(p.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("[WARN]: could not remove the PID file: %v", err)
+		}
+	}
+}
+
+// RemoveStale removes a PID file that isn't locked by a running instance, it
+// fails with ErrLocked otherwise. Like Release, the file is removed while
+// locked so an instance starting at the same time opens it again
+func RemoveStale(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := lock(f); err != nil {
+		return ErrLocked
+	}
+	defer unlock(f)
+
+	return os.Remove(path)
+}
+
+// Read returns the pid written in a PID file
+func Read(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("the PID file %s doesn't contain a valid pid", path)
+	}
+	return pid, nil
+}
+
+// Locked reports if a running instance holds the PID file. The file is
+// opened read-only, as it may belong to another user, and it's only reported
+// as not locked if it doesn't exist or the lock can be taken
+func Locked(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return !errors.Is(err, os.ErrNotExist)
+	}
+	defer f.Close()
+
+	if err := lock(f); err != nil {
+		return true
+	}
+	unlock(f)
+	return false
+}
@@ -0,0 +1,81 @@
+package pidfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestAcquireRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "openuem-ocsp-responder.pid")
+
+	if Locked(path) {
+		t.Fatal("a missing PID file is reported as locked")
+	}
+
+	p, err := Acquire(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pid, err := Read(path); err != nil || pid != os.Getpid() {
+		t.Errorf("expected pid %d, got %d: %v", os.Getpid(), pid, err)
+	}
+	if !Locked(path) {
+		t.Error("the acquired PID file is not reported as locked")
+	}
+	if _, err := Acquire(path); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked acquiring the PID file twice, got %v", err)
+	}
+	if err := RemoveStale(path); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked removing the acquired PID file, got %v", err)
+	}
+
+	p.Release()
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("the PID file was not removed on release: %v", err)
+	}
+	if Locked(path) {
+		t.Error("the released PID file is reported as locked")
+	}
+
+	// it can be acquired again once released
+	p, err = Acquire(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Release()
+}
+
+func TestStalePIDFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "openuem-ocsp-responder.pid")
+	stale := os.Getpid() + 1
+
+	if err := os.WriteFile(path, []byte(strconv.Itoa(stale)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if Locked(path) {
+		t.Fatal("a stale PID file is reported as locked")
+	}
+
+	p, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("could not replace the stale PID file: %v", err)
+	}
+	if pid, err := Read(path); err != nil || pid != os.Getpid() {
+		t.Errorf("expected pid %d, got %d: %v", os.Getpid(), pid, err)
+	}
+	p.Release()
+
+	if err := os.WriteFile(path, []byte(strconv.Itoa(stale)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveStale(path); err != nil {
+		t.Fatalf("could not remove the stale PID file: %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("the stale PID file was not removed: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/open-uem/openuem-ocsp-responder/internal/server/handler"
+)
+
+// AdminServer exposes the management API. It only listens on loopback
+// addresses and Unix domain sockets unless it's protected with mutual TLS
+type AdminServer struct {
+	Handler   *handler.AdminHandler
+	Server    *http.Server
+	Addresses []string
+	TLSConfig *tls.Config
+}
+
+func NewAdmin(h *handler.AdminHandler, addresses []string, tlsConfig *tls.Config) (*AdminServer, error) {
+	if tlsConfig == nil || tlsConfig.ClientAuth != tls.RequireAndVerifyClientCert {
+		for _, address := range addresses {
+			if !IsLocalAddress(address) {
+				return nil, fmt.Errorf("the admin listener can only use %s if mutual TLS is configured", address)
+			}
+		}
+	}
+
+	e := echo.New()
+	e.HideBanner = true
+	h.Register(e)
+
+	return &AdminServer{
+		Handler:   h,
+		Server:    &http.Server{Handler: e},
+		Addresses: addresses,
+		TLSConfig: tlsConfig,
+	}, nil
+}
+
+func (a *AdminServer) Serve() error {
+	return serve(a.Server, a.Addresses, a.TLSConfig)
+}
+
+func (a *AdminServer) Close() {
+	if err := a.Server.Close(); err != nil {
+		log.Println("[ERROR]: could not shutdown admin server")
+	}
+}
+
+// IsLocalAddress reports if the address is a Unix domain socket or a TCP
+// address bound to a loopback interface
+func IsLocalAddress(address string) bool {
+	network, addr := ParseAddress(address)
+	if network == "unix" {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
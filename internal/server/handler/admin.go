@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/open-uem/ent"
+	"github.com/open-uem/openuem-ocsp-responder/internal/metrics"
+)
+
+// AdminHandler serves the management API exposed on the admin listener
+type AdminHandler struct {
+	Handler *Handler
+	// Config returns the effective configuration with secrets redacted
+	Config func() any
+	// Reload generates the configuration again and applies it
+	Reload func() error
+	// Status returns the state of the running responder
+	Status func() any
+}
+
+type CertificateInfo struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	Serial             string    `json:"serial"`
+	NotBefore          time.Time `json:"not_before"`
+	NotAfter           time.Time `json:"not_after"`
+	PublicKeyAlgorithm string    `json:"public_key_algorithm"`
+	SignatureAlgorithm string    `json:"signature_algorithm"`
+	SHA256Fingerprint  string    `json:"sha256_fingerprint"`
+}
+
+type RevocationInfo struct {
+	Serial    string     `json:"serial"`
+	Revoked   bool       `json:"revoked"`
+	Reason    int        `json:"reason,omitempty"`
+	Info      string     `json:"info,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	Backend   string     `json:"backend"`
+}
+
+func (a *AdminHandler) Register(e *echo.Echo) {
+	e.GET("/status", a.GetStatus)
+	e.GET("/config", a.GetConfig)
+	e.GET("/signer", a.GetSigner)
+	e.GET("/cache", a.GetCacheStats)
+	e.POST("/cache/flush", a.FlushCache)
+	e.GET("/revocations/:serial", a.GetRevocation)
+	e.POST("/reload", a.ReloadConfig)
+	e.GET("/metrics", metrics.Handler)
+}
+
+func (a *AdminHandler) GetStatus(c echo.Context) error {
+	if a.Status == nil {
+		return c.JSON(http.StatusNotImplemented, adminError("status is not available"))
+	}
+	return c.JSON(http.StatusOK, a.Status())
+}
+
+func (a *AdminHandler) GetConfig(c echo.Context) error {
+	if a.Config == nil {
+		return c.JSON(http.StatusNotImplemented, adminError("configuration is not available"))
+	}
+	return c.JSON(http.StatusOK, a.Config())
+}
+
+func (a *AdminHandler) GetSigner(c echo.Context) error {
+	caCert, ocspCert, _ := a.Handler.Signer()
+	return c.JSON(http.StatusOK, map[string]*CertificateInfo{
+		"ca":   NewCertificateInfo(caCert),
+		"ocsp": NewCertificateInfo(ocspCert),
+	})
+}
+
+func (a *AdminHandler) GetCacheStats(c echo.Context) error {
+	if a.Handler.Cache == nil {
+		return c.JSON(http.StatusNotFound, adminError("the response cache is not enabled"))
+	}
+	return c.JSON(http.StatusOK, a.Handler.Cache.Stats())
+}
+
+func (a *AdminHandler) FlushCache(c echo.Context) error {
+	if a.Handler.Cache == nil {
+		return c.JSON(http.StatusNotFound, adminError("the response cache is not enabled"))
+	}
+	if err := a.Handler.Cache.Flush(); err != nil {
+		return c.JSON(http.StatusInternalServerError, adminError(fmt.Sprintf("could not flush the response cache: %v", err)))
+	}
+	return c.JSON(http.StatusOK, a.Handler.Cache.Stats())
+}
+
+// GetRevocation looks up a serial number, in decimal or in hexadecimal with
+// the 0x prefix, in the database
+func (a *AdminHandler) GetRevocation(c echo.Context) error {
+	serial, ok := new(big.Int).SetString(strings.ToLower(c.Param("serial")), 0)
+	if !ok || !serial.IsInt64() {
+		return c.JSON(http.StatusBadRequest, adminError("the serial number is not valid"))
+	}
+
+	// The admin API listens before the database is connected
+	model := a.Handler.Model()
+	if model == nil {
+		return c.JSON(http.StatusServiceUnavailable, adminError("database not connected"))
+	}
+
+	revoked, backend, err := model.GetRevoked(c.Request().Context(), serial.Int64())
+	if err != nil && !ent.IsNotFound(err) {
+		return c.JSON(http.StatusServiceUnavailable, adminError(fmt.Sprintf("could not check if certificate has been revoked: %v", err)))
+	}
+
+	info := RevocationInfo{
+		Serial:  serial.String(),
+		Backend: backend,
+	}
+	if revoked != nil {
+		info.Revoked = true
+		info.Reason = revoked.Reason
+		info.Info = revoked.Info
+		info.RevokedAt = &revoked.Revoked
+	}
+	return c.JSON(http.StatusOK, info)
+}
+
+func (a *AdminHandler) ReloadConfig(c echo.Context) error {
+	if a.Reload == nil {
+		return c.JSON(http.StatusNotImplemented, adminError("reload is not supported"))
+	}
+	if err := a.Reload(); err != nil {
+		return c.JSON(http.StatusInternalServerError, adminError(fmt.Sprintf("could not reload the configuration: %v", err)))
+	}
+	return c.JSON(http.StatusOK, map[string]string{"status": "reloaded"})
+}
+
+func NewCertificateInfo(cert *x509.Certificate) *CertificateInfo {
+	if cert == nil {
+		return nil
+	}
+	return &CertificateInfo{
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		Serial:             fmt.Sprintf("%X", cert.SerialNumber),
+		NotBefore:          cert.NotBefore,
+		NotAfter:           cert.NotAfter,
+		PublicKeyAlgorithm: cert.PublicKeyAlgorithm.String(),
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		SHA256Fingerprint:  fmt.Sprintf("%X", sha256.Sum256(cert.Raw)),
+	}
+}
+
+func adminError(message string) map[string]string {
+	return map[string]string{"error": message}
+}
@@ -1,24 +1,80 @@
 package handler
 
 import (
-	"crypto/rsa"
+	"crypto"
 	"crypto/x509"
+	"sync"
 
+	"github.com/open-uem/openuem-ocsp-responder/internal/cache"
 	"github.com/open-uem/openuem-ocsp-responder/internal/models"
+	"github.com/open-uem/openuem-ocsp-responder/internal/signer"
 )
 
 type Handler struct {
-	Model    *models.Model
-	CACert   *x509.Certificate
-	OCSPCert *x509.Certificate
-	OCSPKey  *rsa.PrivateKey
+	Cache *cache.Cache
+	// Readiness returns why the responder can't answer requests yet, such
+	// as the database not being connected, or nil once it's ready
+	Readiness func() error
+
+	mu       sync.RWMutex
+	model    *models.Model
+	options  Options
+	signing  chan struct{}
+	caCert   *x509.Certificate
+	ocspCert *x509.Certificate
+	ocspKey  crypto.Signer
+	response signer.ResponseOptions
 }
 
-func NewHandler(model *models.Model, caCert *x509.Certificate, ocspCert *x509.Certificate, ocspKey *rsa.PrivateKey) *Handler {
+func NewHandler(model *models.Model, caCert *x509.Certificate, ocspCert *x509.Certificate, ocspKey crypto.Signer, response signer.ResponseOptions, c *cache.Cache) *Handler {
 	return &Handler{
-		Model:    model,
-		CACert:   caCert,
-		OCSPCert: ocspCert,
-		OCSPKey:  ocspKey,
+		model:    model,
+		caCert:   caCert,
+		ocspCert: ocspCert,
+		ocspKey:  ocspKey,
+		response: response,
+		Cache:    c,
 	}
 }
+
+// Signer returns the CA certificate, and the OCSP certificate and key used
+// to sign responses
+func (h *Handler) Signer() (*x509.Certificate, *x509.Certificate, crypto.Signer) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.caCert, h.ocspCert, h.ocspKey
+}
+
+// ResponseOptions returns the settings used to sign responses, such as the
+// responder ID type
+func (h *Handler) ResponseOptions() signer.ResponseOptions {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.response
+}
+
+// SetSigner replaces the certificates, the key and the settings used to sign
+// responses, it's used when the configuration is reloaded
+func (h *Handler) SetSigner(caCert *x509.Certificate, ocspCert *x509.Certificate, ocspKey crypto.Signer, response signer.ResponseOptions) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.caCert = caCert
+	h.ocspCert = ocspCert
+	h.ocspKey = ocspKey
+	h.response = response
+}
+
+// Model returns the database model used to look up revocations
+func (h *Handler) Model() *models.Model {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.model
+}
+
+// SetModel replaces the database model, it's used when the database URL
+// changes on a reload
+func (h *Handler) SetModel(model *models.Model) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.model = model
+}
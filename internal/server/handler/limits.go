@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/open-uem/openuem-ocsp-responder/internal/metrics"
+	"golang.org/x/time/rate"
+)
+
+// signingQueueTimeout is the time a request waits for a signing slot before
+// the client is asked to try later
+const signingQueueTimeout = 500 * time.Millisecond
+
+// Limits protects the responder against clients that send too many
+// requests, as signing responses is expensive. Zero values disable a limit
+type Limits struct {
+	// ClientRate is the number of requests per second allowed per client IP
+	ClientRate  float64
+	ClientBurst int
+	// GlobalRate is the number of requests per second allowed in total
+	GlobalRate           float64
+	GlobalBurst          int
+	MaxConcurrentSigning int
+	// MaxRequestSize is the maximum size in bytes of a POST body
+	MaxRequestSize int64
+}
+
+// DefaultLimits returns the limits used when none are configured. The limit
+// per client is disabled, behind a reverse proxy or on a Unix domain socket
+// every client would share the same address
+func DefaultLimits() Limits {
+	return Limits{
+		ClientBurst:          50,
+		MaxConcurrentSigning: 4 * runtime.NumCPU(),
+		MaxRequestSize:       64 * 1024,
+	}
+}
+
+// middlewares returns the rate limiting middlewares for OCSP requests
+func middlewares(limits Limits) []echo.MiddlewareFunc {
+	mws := []echo.MiddlewareFunc{}
+
+	if limits.GlobalRate > 0 {
+		limiter := rate.NewLimiter(rate.Limit(limits.GlobalRate), max(limits.GlobalBurst, 1))
+		mws = append(mws, func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				if !limiter.Allow() {
+					return throttled(c)
+				}
+				return next(c)
+			}
+		})
+	}
+
+	if limits.ClientRate > 0 {
+		store := middleware.NewRateLimiterMemoryStoreWithConfig(middleware.RateLimiterMemoryStoreConfig{
+			Rate:      rate.Limit(limits.ClientRate),
+			Burst:     max(limits.ClientBurst, 1),
+			ExpiresIn: 3 * time.Minute,
+		})
+		mws = append(mws, middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
+			Store: store,
+			// headers such as X-Forwarded-For are ignored so a client can't
+			// choose its own identity
+			IdentifierExtractor: func(c echo.Context) (string, error) {
+				host, _, err := net.SplitHostPort(c.Request().RemoteAddr)
+				if err != nil {
+					return c.Request().RemoteAddr, nil
+				}
+				return host, nil
+			},
+			DenyHandler: func(c echo.Context, identifier string, err error) error {
+				return throttled(c)
+			},
+		}))
+	}
+
+	return mws
+}
+
+// acquireSigning waits for a free signing slot. The returned function
+// releases it
+func (h *Handler) acquireSigning(ctx context.Context) (func(), bool) {
+	h.mu.RLock()
+	signing := h.signing
+	h.mu.RUnlock()
+
+	if signing == nil {
+		return func() {}, true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, signingQueueTimeout)
+	defer cancel()
+
+	select {
+	case signing <- struct{}{}:
+		return func() { <-signing }, true
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+func throttled(c echo.Context) error {
+	metrics.Throttled.Inc()
+	metrics.TryLater.Inc()
+	return sendOCSPError(c, http.StatusTooManyRequests, tryLater)
+}
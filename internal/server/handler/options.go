@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"crypto"
+	"crypto/x509"
+	"slices"
+	"strings"
+)
+
+// Options holds the settings of the OCSP endpoint
+type Options struct {
+	Limits Limits
+	// PathPrefix is the URL path the responder is served under, e.g. /ocsp/
+	PathPrefix string
+	// LenientContentType accepts POST requests without the
+	// application/ocsp-request content type required by RFC 6960
+	LenientContentType bool
+	// CertIDHashes are the hash algorithms accepted in the CertID of the
+	// requests, any other is rejected as a malformed request
+	CertIDHashes []crypto.Hash
+	// RequestSignature sets if the requests must be signed by a client whose
+	// certificate chains up to RequestTrustStore
+	RequestSignature  RequestSignaturePolicy
+	RequestTrustStore *x509.CertPool
+}
+
+// DefaultOptions returns the options used when none are configured
+func DefaultOptions() Options {
+	return Options{
+		Limits:     DefaultLimits(),
+		PathPrefix: "/",
+		CertIDHashes: []crypto.Hash{
+			crypto.SHA1,
+			crypto.SHA256,
+			crypto.SHA384,
+			crypto.SHA512,
+		},
+	}
+}
+
+// SetOptions configures the OCSP endpoint. The routes must be registered
+// again for a new path prefix or new rate limits to be applied
+func (h *Handler) SetOptions(o Options) {
+	o.PathPrefix = normalizePrefix(o.PathPrefix)
+
+	var signing chan struct{}
+	if o.Limits.MaxConcurrentSigning > 0 {
+		signing = make(chan struct{}, o.Limits.MaxConcurrentSigning)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.options = o
+	h.signing = signing
+}
+
+// Options returns the settings of the OCSP endpoint
+func (h *Handler) Options() Options {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.options
+}
+
+// isHashAllowed reports if the CertID hash algorithm of a request is in the
+// allow-list, every algorithm is accepted if the list is empty
+func (o Options) isHashAllowed(hash crypto.Hash) bool {
+	return len(o.CertIDHashes) == 0 || slices.Contains(o.CertIDHashes, hash)
+}
+
+// normalizePrefix makes sure the prefix starts and ends with a slash
+func normalizePrefix(prefix string) string {
+	prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+	if prefix == "" {
+		return "/"
+	}
+	return "/" + prefix + "/"
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+
+	"github.com/open-uem/openuem-ocsp-responder/internal/signer"
+	"golang.org/x/crypto/ocsp"
+)
+
+// These structures reflect the ASN.1 structure of an OCSP request, see RFC
+// 6960 section 4.1.1. ocsp.ParseRequest can't be used as it decodes the
+// requestorName as a Name instead of a GeneralName, so it fails with every
+// signed request, and it drops the signature
+type ocspRequest struct {
+	TBSRequest        tbsRequest
+	OptionalSignature requestSignature `asn1:"explicit,tag:0,optional"`
+}
+
+type tbsRequest struct {
+	Raw               asn1.RawContent
+	Version           int           `asn1:"explicit,tag:0,default:0,optional"`
+	RequestorName     asn1.RawValue `asn1:"explicit,tag:1,optional"`
+	RequestList       []singleRequest
+	RequestExtensions []pkix.Extension `asn1:"explicit,tag:2,optional"`
+}
+
+type singleRequest struct {
+	Cert certID
+}
+
+type certID struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	NameHash      []byte
+	IssuerKeyHash []byte
+	SerialNumber  *big.Int
+}
+
+type requestSignature struct {
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certs              []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+// parseRequest parses a DER encoded OCSP request. Like ocsp.ParseRequest
+// only the first certificate of the request list is answered
+func parseRequest(der []byte) (*ocspRequest, *ocsp.Request, error) {
+	var req ocspRequest
+	rest, err := asn1.Unmarshal(der, &req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rest) > 0 {
+		return nil, nil, errors.New("trailing data in OCSP request")
+	}
+
+	if len(req.TBSRequest.RequestList) == 0 {
+		return nil, nil, errors.New("OCSP request contains no request body")
+	}
+	cert := req.TBSRequest.RequestList[0].Cert
+
+	hashFunc := signer.HashAlgorithmFromOID(cert.HashAlgorithm.Algorithm)
+	if hashFunc == 0 {
+		return nil, nil, errors.New("OCSP request uses unknown hash function")
+	}
+
+	return &req, &ocsp.Request{
+		HashAlgorithm:  hashFunc,
+		IssuerNameHash: cert.NameHash,
+		IssuerKeyHash:  cert.IssuerKeyHash,
+		SerialNumber:   cert.SerialNumber,
+	}, nil
+}
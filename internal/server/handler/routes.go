@@ -1,10 +1,39 @@
 package handler
 
 import (
+	"net/http"
+	"strings"
+
 	"github.com/labstack/echo/v4"
 )
 
+// allowedMethods are the methods defined for OCSP over HTTP in RFC 6960
+// Appendix A, plus HEAD for GET URLs
+var allowedMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+
 func (h *Handler) Register(e *echo.Echo) {
-	e.GET("/*", h.Verify)
-	e.POST("/", h.Verify)
+	options := h.Options()
+	prefix := normalizePrefix(options.PathPrefix)
+	mws := middlewares(options.Limits)
+
+	health := func(c echo.Context) error {
+		return healthCheck(c, h)
+	}
+	e.GET("/health", health)
+	e.HEAD("/health", health)
+
+	e.GET(prefix+"*", h.Verify, mws...)
+	e.HEAD(prefix+"*", h.Verify, mws...)
+	e.POST(prefix, h.Verify, mws...)
+	if prefix != "/" {
+		e.POST(strings.TrimSuffix(prefix, "/"), h.Verify, mws...)
+	}
+
+	notAllowed := []string{http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace}
+	e.Match(notAllowed, prefix+"*", methodNotAllowed)
+}
+
+func methodNotAllowed(c echo.Context) error {
+	c.Response().Header().Set("Allow", strings.Join(allowedMethods, ", "))
+	return c.NoContent(http.StatusMethodNotAllowed)
 }
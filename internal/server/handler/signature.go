@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/open-uem/openuem-ocsp-responder/internal/signer"
+)
+
+// RequestSignaturePolicy sets how the optional signature of the requests,
+// see RFC 6960 section 4.1.2, is handled
+type RequestSignaturePolicy int
+
+const (
+	// IgnoreSignature answers every request without checking signatures
+	IgnoreSignature RequestSignaturePolicy = iota
+	// VerifySignatureIfPresent rejects signed requests whose signature is
+	// not valid, unsigned requests are answered
+	VerifySignatureIfPresent
+	// RequireSignature rejects requests without a valid signature
+	RequireSignature
+)
+
+func (p RequestSignaturePolicy) String() string {
+	switch p {
+	case VerifySignatureIfPresent:
+		return "verify-if-present"
+	case RequireSignature:
+		return "require"
+	default:
+		return "ignore"
+	}
+}
+
+// ParseRequestSignaturePolicy parses ignore, verify-if-present or require.
+// An empty string selects IgnoreSignature
+func ParseRequestSignaturePolicy(value string) (RequestSignaturePolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "ignore":
+		return IgnoreSignature, nil
+	case "verify-if-present":
+		return VerifySignatureIfPresent, nil
+	case "require":
+		return RequireSignature, nil
+	default:
+		return IgnoreSignature, fmt.Errorf("unknown request signature policy %q, use ignore, verify-if-present or require", value)
+	}
+}
+
+var errSignatureMissing = errors.New("the request is not signed")
+
+// verifyRequestSignature checks the signature of a request. The first
+// certificate included in the request must have signed it, be valid for
+// client authentication and chain up to the trust store, the rest are used
+// as intermediates. It returns the signer certificate or errSignatureMissing
+// if the request is not signed
+func verifyRequestSignature(req *ocspRequest, trustStore *x509.CertPool) (*x509.Certificate, error) {
+	signature := req.OptionalSignature
+	if signature.SignatureAlgorithm.Algorithm == nil {
+		return nil, errSignatureMissing
+	}
+
+	if len(signature.Certs) == 0 {
+		return nil, errors.New("the request doesn't include the signer certificate")
+	}
+	if trustStore == nil {
+		return nil, errors.New("no trust store is configured to verify signed requests")
+	}
+
+	certs := []*x509.Certificate{}
+	for _, raw := range signature.Certs {
+		cert, err := x509.ParseCertificate(raw.FullBytes)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse the certificates of the request: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         trustStore,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return nil, fmt.Errorf("the signer certificate is not trusted: %v", err)
+	}
+
+	algorithm := signer.SignatureAlgorithmFromAI(signature.SignatureAlgorithm)
+	if algorithm == x509.UnknownSignatureAlgorithm {
+		return nil, fmt.Errorf("unsupported signature algorithm %v", signature.SignatureAlgorithm.Algorithm)
+	}
+	if err := certs[0].CheckSignature(algorithm, req.TBSRequest.Raw, signature.Signature.RightAlign()); err != nil {
+		return nil, fmt.Errorf("the request signature is not valid: %v", err)
+	}
+
+	return certs[0], nil
+}
+
+// checkRequestSignature applies the signature policy to a request, it
+// returns an error if the request must be rejected
+func checkRequestSignature(req *ocspRequest, options Options) error {
+	if options.RequestSignature == IgnoreSignature {
+		return nil
+	}
+
+	_, err := verifyRequestSignature(req, options.RequestTrustStore)
+	if errors.Is(err, errSignatureMissing) && options.RequestSignature == VerifySignatureIfPresent {
+		return nil
+	}
+	return err
+}
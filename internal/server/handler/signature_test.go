@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/open-uem/openuem-ocsp-responder/internal/models"
+	"github.com/open-uem/openuem-ocsp-responder/internal/signer"
+	"golang.org/x/crypto/ocsp"
+)
+
+var oidSignatureECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+
+// newCert issues a certificate for a new P-256 key from template, it's self
+// signed if parent is nil
+func newCert(t *testing.T, template *x509.Certificate, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, crypto.Signer) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	template.NotBefore = now.Add(-time.Hour)
+	template.NotAfter = now.Add(time.Hour)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func newCA(t *testing.T, serial int64, name string) (*x509.Certificate, crypto.Signer) {
+	t.Helper()
+
+	return newCert(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: name},
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, nil, nil)
+}
+
+// signRequest adds a signature made with key to an OCSP request, including
+// certs as the signer certificates
+func signRequest(t *testing.T, der []byte, key crypto.Signer, certs ...*x509.Certificate) []byte {
+	t.Helper()
+
+	req, _, err := parseRequest(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest := sha256.Sum256(req.TBSRequest.Raw)
+	signature, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.OptionalSignature = requestSignature{
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSignatureECDSAWithSHA256},
+		Signature:          asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)},
+	}
+	for _, cert := range certs {
+		req.OptionalSignature.Certs = append(req.OptionalSignature.Certs, asn1.RawValue{FullBytes: cert.Raw})
+	}
+
+	signed, err := asn1.Marshal(*req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestRequestSignature(t *testing.T) {
+	dbUrl := "sqlite://" + filepath.Join(t.TempDir(), "openuem.db")
+	if err := models.Migrate(dbUrl); err != nil {
+		t.Fatal(err)
+	}
+	model, err := models.New(dbUrl, nil, models.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer model.Close()
+
+	// the CA answered by the responder, its OCSP signer and the certificate
+	// whose status is requested
+	ca, caKey := newCA(t, 1, "OpenUEM Test CA")
+	responder, responderKey := newCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "OpenUEM Test OCSP Responder"},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+	}, ca, caKey)
+	leaf, _ := newCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(4242),
+		Subject:      pkix.Name{CommonName: "agent"},
+	}, ca, caKey)
+
+	// the clients signing the requests, only the first CA is trusted
+	clientCA, clientCAKey := newCA(t, 3, "OpenUEM Test Client CA")
+	client, clientKey := newCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(4),
+		Subject:      pkix.Name{CommonName: "client"},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, clientCA, clientCAKey)
+	server, serverKey := newCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(5),
+		Subject:      pkix.Name{CommonName: "server"},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, clientCA, clientCAKey)
+	untrustedCA, untrustedCAKey := newCA(t, 6, "Untrusted CA")
+	untrusted, untrustedKey := newCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(7),
+		Subject:      pkix.Name{CommonName: "untrusted"},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, untrustedCA, untrustedCAKey)
+
+	unsigned, err := ocsp.CreateRequest(leaf, ca, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := map[string][]byte{
+		"unsigned":         unsigned,
+		"valid signature":  signRequest(t, unsigned, clientKey, client),
+		"bad signature":    signRequest(t, unsigned, untrustedKey, client),
+		"untrusted chain":  signRequest(t, unsigned, untrustedKey, untrusted, untrustedCA),
+		"missing cert":     signRequest(t, unsigned, clientKey),
+		"server key usage": signRequest(t, unsigned, serverKey, server),
+	}
+
+	trustStore := x509.NewCertPool()
+	trustStore.AddCert(clientCA)
+
+	tests := []struct {
+		policy   RequestSignaturePolicy
+		accepted []string
+	}{
+		{IgnoreSignature, []string{"unsigned", "valid signature", "bad signature", "untrusted chain", "missing cert", "server key usage"}},
+		{VerifySignatureIfPresent, []string{"unsigned", "valid signature"}},
+		{RequireSignature, []string{"valid signature"}},
+	}
+
+	for _, tt := range tests {
+		h := NewHandler(model, ca, responder, responderKey, signer.ResponseOptions{}, nil)
+		options := DefaultOptions()
+		options.RequestSignature = tt.policy
+		options.RequestTrustStore = trustStore
+		h.SetOptions(options)
+
+		e := echo.New()
+		h.Register(e)
+
+		for name, der := range requests {
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(der))
+			req.Header.Set("Content-Type", "application/ocsp-request")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if !slices.Contains(tt.accepted, name) {
+				if rec.Code != http.StatusForbidden || !bytes.Equal(rec.Body.Bytes(), []byte{0x30, 0x03, 0x0A, 0x01, sigRequired}) {
+					t.Errorf("%s, %s: expected sigRequired with 403, got %d %X", tt.policy, name, rec.Code, rec.Body.Bytes())
+				}
+				continue
+			}
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("%s, %s: expected 200, got %d %X", tt.policy, name, rec.Code, rec.Body.Bytes())
+				continue
+			}
+			response, err := ocsp.ParseResponseForCert(rec.Body.Bytes(), leaf, ca)
+			if err != nil {
+				t.Errorf("%s, %s: could not parse the response: %v", tt.policy, name, err)
+				continue
+			}
+			if response.Status != ocsp.Good {
+				t.Errorf("%s, %s: expected a good status, got %d", tt.policy, name, response.Status)
+			}
+		}
+	}
+}
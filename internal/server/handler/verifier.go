@@ -2,6 +2,7 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"crypto/x509"
 	"crypto/x509/pkix"
@@ -11,68 +12,138 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"mime"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/open-uem/ent"
+	"github.com/open-uem/openuem-ocsp-responder/internal/metrics"
+	"github.com/open-uem/openuem-ocsp-responder/internal/signer"
 	"golang.org/x/crypto/ocsp"
 )
 
 var (
 	malformedRequest = byte(1)
 	internalError    = byte(2)
+	tryLater         = byte(3)
+	sigRequired      = byte(5)
 )
 
+// errNotConnected is returned by the revocation lookups until the database is
+// connected
+var errNotConnected = errors.New("not connected with the database")
+
 func (h *Handler) Verify(c echo.Context) error {
 	var req *ocsp.Request
 	var requestBody []byte
 	var err error
 
-	if c.Request().Method == "POST" {
-		requestBody, err = io.ReadAll(c.Request().Body)
+	metrics.Requests.Inc()
+
+	options := h.Options()
+
+	if c.Request().Method == http.MethodPost {
+		if !options.LenientContentType && !isOCSPRequestContentType(c.Request().Header.Get("Content-Type")) {
+			return sendOCSPError(c, http.StatusUnsupportedMediaType, malformedRequest)
+		}
+
+		body := io.Reader(c.Request().Body)
+		if options.Limits.MaxRequestSize > 0 {
+			body = io.LimitReader(body, options.Limits.MaxRequestSize+1)
+		}
+
+		requestBody, err = io.ReadAll(body)
 		if err != nil {
 			return sendOCSPError(c, http.StatusBadRequest, malformedRequest)
 		}
-	}
 
-	if c.Request().Method == "GET" {
-		uri := c.Request().URL.Path
-		if strings.Contains(uri, "health") {
-			return healthCheck(c, h)
+		if options.Limits.MaxRequestSize > 0 && int64(len(requestBody)) > options.Limits.MaxRequestSize {
+			return sendOCSPError(c, http.StatusRequestEntityTooLarge, malformedRequest)
 		}
+	}
 
-		requestBody, err = base64.StdEncoding.DecodeString(strings.TrimPrefix(uri, "/"))
+	// HEAD is answered as GET, the body is discarded by the HTTP server
+	if c.Request().Method == http.MethodGet || c.Request().Method == http.MethodHead {
+		uri := c.Request().URL.Path
+		encoded := strings.TrimPrefix(uri, normalizePrefix(options.PathPrefix))
+		requestBody, err = base64.StdEncoding.DecodeString(encoded)
 		if err != nil {
 			return sendOCSPError(c, http.StatusBadRequest, malformedRequest)
 		}
 	}
 
 	// Parse request
-	req, err = ocsp.ParseRequest(requestBody)
+	// requests using a CertID hash algorithm unknown to the parser, such as
+	// MD5, fail here and are malformed for the responder too
+	rawReq, req, err := parseRequest(requestBody)
 	if err != nil {
-		return sendOCSPError(c, http.StatusInternalServerError, internalError)
+		return sendOCSPError(c, http.StatusBadRequest, malformedRequest)
+	}
+
+	if err := checkRequestSignature(rawReq, options); err != nil {
+		log.Printf("[WARN]: rejected request for serial %s: %v", req.SerialNumber.String(), err)
+		metrics.SignatureRequired.Inc()
+		return sendOCSPError(c, http.StatusForbidden, sigRequired)
+	}
+
+	if !options.isHashAllowed(req.HashAlgorithm) {
+		log.Printf("[WARN]: rejected request for serial %s using the %s CertID hash algorithm", req.SerialNumber.String(), req.HashAlgorithm.String())
+		return sendOCSPError(c, http.StatusBadRequest, malformedRequest)
+	}
+
+	caCert, ocspCert, ocspKey := h.Signer()
+
+	// Nothing can be answered until the configuration is loaded
+	if caCert == nil || ocspCert == nil || ocspKey == nil {
+		metrics.TryLater.Inc()
+		return sendOCSPError(c, http.StatusServiceUnavailable, tryLater)
 	}
 
 	// Verify issuer name and key hashes
-	if err := verifyIssuer(h.CACert, req); err != nil {
+	if err := verifyIssuer(caCert, req); err != nil {
 		return sendOCSPError(c, http.StatusInternalServerError, malformedRequest)
 	}
 
 	// create response template
-	responseTemplate := h.createResponseTemplate(req)
+	responseTemplate, _, err := h.CreateResponseTemplate(c.Request().Context(), req)
+	if err != nil {
+		return h.sendCachedResponse(c, req)
+	}
 
-	// make a response to return
-	response, err := ocsp.CreateResponse(h.CACert, h.OCSPCert, responseTemplate, h.OCSPKey)
+	// make a response to return, signing is expensive so the number of
+	// concurrent operations is limited
+	release, ok := h.acquireSigning(c.Request().Context())
+	if !ok {
+		return throttled(c)
+	}
+	response, err := signer.CreateResponse(caCert, ocspCert, responseTemplate, ocspKey, h.ResponseOptions())
+	release()
 	if err != nil {
 		return sendOCSPError(c, http.StatusInternalServerError, internalError)
 	}
 
+	metrics.Responses.Inc()
+
+	// keep it as the last known good response
+	if h.Cache != nil {
+		if err := h.Cache.Put(req.HashAlgorithm, req.IssuerKeyHash, response); err != nil {
+			log.Printf("[WARN]: could not cache response for serial %s: %v", req.SerialNumber.String(), err)
+		}
+	}
+
 	// send response
 	return sendOCSPResponse(c, responseTemplate, response)
 }
 
+// isOCSPRequestContentType reports if the content type of a POST request is
+// application/ocsp-request, as required by RFC 6960 Appendix A
+func isOCSPRequestContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/ocsp-request"
+}
+
 func sendOCSPError(c echo.Context, code int, status byte) error {
 	c.Response().Status = code
 	// Reference: https://github.com/cloudflare/cfssl/blob/master/ocsp/responder.go#L33
@@ -80,35 +151,65 @@ func sendOCSPError(c echo.Context, code int, status byte) error {
 	return nil
 }
 
-func (h *Handler) createResponseTemplate(req *ocsp.Request) ocsp.Response {
+// CreateResponseTemplate builds the response for the certificate in req, it's
+// shared by the responder and the pregenerate command. It returns an error if
+// the revocation status couldn't be checked because the database is
+// unavailable or too slow, and the name of the database that served the lookup
+func (h *Handler) CreateResponseTemplate(ctx context.Context, req *ocsp.Request) (ocsp.Response, string, error) {
 	serial := req.SerialNumber
+	_, ocspCert, _ := h.Signer()
 
 	// construct response template
 	responseTemplate := ocsp.Response{
 		SerialNumber: req.SerialNumber,
-		Certificate:  h.OCSPCert,
+		Certificate:  ocspCert,
 		IssuerHash:   req.HashAlgorithm,
 		ThisUpdate:   time.Now().Truncate(time.Hour),
 		NextUpdate:   time.Now().AddDate(0, 0, 1).UTC(),
 	}
 
-	// check if certificate has been revoked querying the database
-	revoked, err := h.Model.GetRevoked(serial.Int64())
+	// check if certificate has been revoked querying the database, which is
+	// only set once connected
+	model := h.Model()
+	if model == nil {
+		return responseTemplate, "", errNotConnected
+	}
+	revoked, backend, err := model.GetRevoked(ctx, serial.Int64())
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		log.Printf("[ERROR]: revocation lookup timed out for serial %s", serial.String())
+		return responseTemplate, backend, err
+	}
 	if err != nil && !ent.IsNotFound(err) {
-		log.Println("... could not check if certificate has been revoked")
-		responseTemplate.Status = ocsp.Unknown
+		log.Printf("[ERROR]: could not check if certificate %s has been revoked: %v", serial.String(), err)
+		return responseTemplate, backend, err
+	}
+
+	metrics.BackendLookups.Inc(backend)
+
+	// complete response based on status
+	if revoked != nil {
+		responseTemplate.Status = ocsp.Revoked
+		responseTemplate.RevocationReason = revoked.Reason
+		responseTemplate.RevokedAt = time.Now()
 	} else {
-		// complete response based on status
-		if revoked != nil {
-			responseTemplate.Status = ocsp.Revoked
-			responseTemplate.RevocationReason = revoked.Reason
-			responseTemplate.RevokedAt = time.Now()
-		} else {
-			responseTemplate.Status = ocsp.Good
+		responseTemplate.Status = ocsp.Good
+	}
+
+	return responseTemplate, backend, nil
+}
+
+// sendCachedResponse answers with the last known good response while the
+// database is unavailable, or asks the client to try later if there's none
+func (h *Handler) sendCachedResponse(c echo.Context, req *ocsp.Request) error {
+	if h.Cache != nil {
+		if entry, ok := h.Cache.Get(req.HashAlgorithm, req.IssuerKeyHash, req.SerialNumber); ok {
+			metrics.StaleResponses.Inc()
+			return sendOCSPResponse(c, *entry.Response, entry.Raw)
 		}
 	}
 
-	return responseTemplate
+	metrics.TryLater.Inc()
+	return sendOCSPError(c, http.StatusServiceUnavailable, tryLater)
 }
 
 func sendOCSPResponse(c echo.Context, responseTemplate ocsp.Response, response []byte) error {
@@ -122,8 +223,20 @@ func sendOCSPResponse(c echo.Context, responseTemplate ocsp.Response, response [
 	return nil
 }
 
+// healthCheck answers the public health endpoint. The reason why the
+// responder isn't ready is only reported by the status of the admin API
 func healthCheck(c echo.Context, h *Handler) error {
-	if _, err := h.Model.GetRevoked(0); err != nil {
+	if h.Readiness != nil {
+		if err := h.Readiness(); err != nil {
+			return c.String(http.StatusServiceUnavailable, "OCSP Responder is not ready")
+		}
+	}
+
+	model := h.Model()
+	if model == nil {
+		return c.String(http.StatusServiceUnavailable, "OCSP Responder is not ready")
+	}
+	if _, _, err := model.GetRevoked(c.Request().Context(), 0); err != nil {
 		if ent.IsNotFound(err) {
 			return c.String(http.StatusOK, "OCSP Responder is healthy")
 		} else {
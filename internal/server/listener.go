@@ -0,0 +1,79 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// Listen opens a listener for a configured address, which can be:
+//   - a TCP host:port pair, e.g. 10.0.0.1:8000, [::1]:8000 or :8000
+//   - a port number alone, bound on every interface
+//   - a Unix domain socket, e.g. unix:/run/openuem/ocsp.sock or /run/openuem/ocsp.sock
+func Listen(address string) (net.Listener, error) {
+	network, addr := ParseAddress(address)
+
+	if network == "unix" {
+		if err := removeStaleSocket(addr); err != nil {
+			return nil, err
+		}
+	}
+
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("could not listen on %s: %v", address, err)
+	}
+	return l, nil
+}
+
+// removeStaleSocket removes a socket left behind by a previous instance. The
+// socket is only removed if nobody accepts connections on it, one that is
+// still in use by another process is never taken over
+func removeStaleSocket(path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode().Type() != fs.ModeSocket {
+		// not a socket, listening fails with a clear error
+		return nil
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("could not listen on %s: the socket is in use by another process", path)
+	}
+	if !connectionRefused(err) {
+		return fmt.Errorf("could not check if socket %s is in use: %v", path, err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("could not remove stale socket %s: %v", path, err)
+	}
+	return nil
+}
+
+// ParseAddress returns the network and the address used to listen on a
+// configured address
+func ParseAddress(address string) (string, string) {
+	address = strings.TrimSpace(address)
+
+	switch {
+	case strings.HasPrefix(address, "unix:"):
+		return "unix", strings.TrimPrefix(address, "unix:")
+	case strings.HasPrefix(address, "/"), strings.HasPrefix(address, "@"):
+		return "unix", address
+	case !strings.Contains(address, ":"):
+		return "tcp", ":" + address
+	default:
+		return "tcp", address
+	}
+}
@@ -0,0 +1,11 @@
+package server
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+func connectionRefused(err error) bool {
+	return errors.Is(err, windows.WSAECONNREFUSED)
+}
@@ -1,41 +1,196 @@
 package server
 
 import (
-	"crypto/rsa"
+	"crypto"
+	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"log"
+	"net"
 	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
 
 	"github.com/labstack/echo/v4"
+	"github.com/open-uem/openuem-ocsp-responder/internal/cache"
 	"github.com/open-uem/openuem-ocsp-responder/internal/models"
 	"github.com/open-uem/openuem-ocsp-responder/internal/server/handler"
+	"github.com/open-uem/openuem-ocsp-responder/internal/signer"
 )
 
 type WebServer struct {
-	Handler *handler.Handler
-	Server  *http.Server
-	Address string
+	Handler   *handler.Handler
+	Server    *http.Server
+	Addresses []string
+
+	router    atomic.Pointer[echo.Echo]
+	mu        sync.Mutex
+	listeners map[string]net.Listener
+	errs      chan error
 }
 
-func New(m *models.Model, address string, caCert *x509.Certificate, ocspCert *x509.Certificate, ocspKey *rsa.PrivateKey) *WebServer {
+func New(m *models.Model, addresses []string, caCert *x509.Certificate, ocspCert *x509.Certificate, ocspKey crypto.Signer, response signer.ResponseOptions, c *cache.Cache, options handler.Options) *WebServer {
 	w := WebServer{}
-	w.Handler = handler.NewHandler(m, caCert, ocspCert, ocspKey)
-	w.Address = address
+	w.Handler = handler.NewHandler(m, caCert, ocspCert, ocspKey, response, c)
+	w.Handler.SetOptions(options)
+	w.Addresses = addresses
+
+	// The server is created here as it may be closed before Serve runs, in
+	// which case it doesn't serve the listeners
+	w.Server = &http.Server{
+		Handler: http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			w.router.Load().ServeHTTP(rw, r)
+		}),
+	}
 	return &w
 }
 
+// Serve listens on every address and blocks until the server is closed. If
+// any of the addresses can't be bound, none of them is served
 func (w *WebServer) Serve() error {
+	w.Reroute()
+
+	w.mu.Lock()
+	listeners, err := listenAll(w.Addresses, nil)
+	if err != nil {
+		w.mu.Unlock()
+		return err
+	}
+	w.errs = make(chan error, 1)
+	w.listeners = map[string]net.Listener{}
+	for i, l := range listeners {
+		w.listeners[w.Addresses[i]] = l
+		w.serveListener(l)
+	}
+	w.mu.Unlock()
+
+	return <-w.errs
+}
+
+// Reroute registers the routes on a new router, so that changes of the
+// handler options such as the path prefix or the rate limits are applied to
+// new requests
+func (w *WebServer) Reroute() {
 	e := echo.New()
 	w.Handler.Register(e)
-	w.Server = &http.Server{
-		Addr:    w.Address,
-		Handler: e,
-	}
 	// e.Use(middleware.Logger()) // -> TODO set an env variable for debug
-	return w.Server.ListenAndServe()
+	w.router.Store(e)
+}
+
+// Rebind makes the server listen on addresses. The new addresses are bound
+// before any listener is closed, so if one of them can't be bound the server
+// keeps listening on the previous ones. An address can't be moved to another
+// interface on the same port, as both listeners would be open at once
+func (w *WebServer) Rebind(addresses []string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// the server isn't listening yet
+	if w.listeners == nil {
+		w.Addresses = addresses
+		return nil
+	}
+
+	added := []string{}
+	for _, address := range addresses {
+		if _, ok := w.listeners[address]; !ok {
+			added = append(added, address)
+		}
+	}
+
+	listeners, err := listenAll(added, nil)
+	if err != nil {
+		return err
+	}
+
+	for address, l := range w.listeners {
+		if !slices.Contains(addresses, address) {
+			log.Printf("[INFO]: stopped listening on %s", l.Addr().String())
+			l.Close()
+			delete(w.listeners, address)
+		}
+	}
+
+	for i, l := range listeners {
+		w.listeners[added[i]] = l
+		w.serveListener(l)
+	}
+	w.Addresses = addresses
+	return nil
+}
+
+// serveListener serves l until it's closed. The first error stops the
+// server and is returned by Serve, except for listeners closed by Rebind
+func (w *WebServer) serveListener(l net.Listener) {
+	log.Printf("[INFO]: listening on %s", l.Addr().String())
+	go func() {
+		err := w.Server.Serve(l)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != http.ErrServerClosed {
+			w.Server.Close()
+		}
+		select {
+		case w.errs <- err:
+		default:
+		}
+	}()
+}
+
+// listenAll opens a listener for every address, using TLS if tlsConfig is
+// set. If any of the addresses can't be bound, the others are closed
+func listenAll(addresses []string, tlsConfig *tls.Config) ([]net.Listener, error) {
+	listeners := []net.Listener{}
+	for _, address := range addresses {
+		l, err := Listen(address)
+		if err != nil {
+			for _, opened := range listeners {
+				opened.Close()
+			}
+			return nil, err
+		}
+		if tlsConfig != nil {
+			l = tls.NewListener(l, tlsConfig)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// serve listens on every address, using TLS if tlsConfig is set, and blocks
+// until the server is closed
+func serve(s *http.Server, addresses []string, tlsConfig *tls.Config) error {
+	listeners, err := listenAll(addresses, tlsConfig)
+	if err != nil {
+		return err
+	}
+
+	errs := make(chan error, len(listeners))
+	for _, l := range listeners {
+		log.Printf("[INFO]: listening on %s", l.Addr().String())
+		go func() {
+			errs <- s.Serve(l)
+		}()
+	}
+
+	for range listeners {
+		if serveErr := <-errs; serveErr != http.ErrServerClosed && err == nil {
+			err = serveErr
+			s.Close()
+		}
+	}
+	if err == nil {
+		err = http.ErrServerClosed
+	}
+	return err
 }
 
 func (w *WebServer) Close() {
+	if w.Server == nil {
+		return
+	}
 	if err := w.Server.Close(); err != nil {
 		log.Println("[ERROR]: could not shutdown web server")
 	}
@@ -26,19 +26,30 @@ func main() {
 	w.TaskScheduler.Start()
 	log.Println("[INFO]: task scheduler has been started")
 
+	w.LoadConfig = w.GenerateOCSPResponderConfig
+
 	if err := w.GenerateOCSPResponderConfig(); err != nil {
-		log.Printf("[ERROR]: could not generate config for OCSP responder: %v", err)
-		if err := w.StartGenerateOCSPResponderConfigJob(); err != nil {
-			log.Fatalf("[FATAL]: could not start job to generate config for OCSP responder: %v", err)
-		}
+		log.Printf("[ERROR]: could not generate config for OCSP responder: %v, run 'openuem-ocsp-responder config validate --service' to check every setting", err)
+		w.StartGenerateOCSPResponderConfigJob(err)
 	}
 
 	w.StartWorker()
 
+	// Reload the configuration on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	w.ReloadOnSignal(hup)
+
 	// Keep the connection alive
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
-	<-done
-
-	w.StopWorker()
+	select {
+	case <-done:
+		w.StopWorker()
+	case err := <-w.Failed():
+		// systemd restarts the service according to its restart policy
+		log.Printf("[ERROR]: the OCSP responder can't start: %v", err)
+		w.StopWorker()
+		os.Exit(1)
+	}
 }
@@ -4,6 +4,7 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/go-co-op/gocron/v2"
 	"github.com/open-uem/openuem-ocsp-responder/internal/common"
@@ -24,13 +25,22 @@ func main() {
 	w.TaskScheduler.Start()
 	log.Println("[INFO]: task scheduler has been started")
 
+	w.LoadConfig = w.GenerateOCSPResponderConfig
+
 	if err := w.GenerateOCSPResponderConfig(); err != nil {
-		log.Printf("[ERROR]: could not generate config for OCSP responder: %v", err)
-		if err := w.StartGenerateOCSPResponderConfigJob(); err != nil {
-			log.Fatalf("[FATAL]: could not start job to generate config for OCSP responder: %v", err)
-		}
+		log.Printf("[ERROR]: could not generate config for OCSP responder: %v, run 'openuem-ocsp-responder config validate --service' to check every setting", err)
+		w.StartGenerateOCSPResponderConfigJob(err)
 	}
 
+	// The service exits with an error, so the recovery actions of the
+	// service apply, if it gives up
+	go func() {
+		err := <-w.Failed()
+		log.Printf("[ERROR]: the OCSP responder can't start: %v", err)
+		w.StopWorker()
+		os.Exit(1)
+	}()
+
 	s := utils.NewOpenUEMWindowsService()
 	s.ServiceStart = w.StartWorker
 	s.ServiceStop = w.StopWorker
@@ -0,0 +1,68 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/youmark/pkcs8"
+)
+
+// ReadPrivateKey reads the OCSP signing key from a PEM file, see
+// ParsePrivateKey
+func ReadPrivateKey(path string, passphrase string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ParsePrivateKey(data, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the private key in %s: %v", path, err)
+	}
+	return key, nil
+}
+
+// ParsePrivateKey parses a PEM encoded OCSP signing key. Keys can be in
+// PKCS#1, SEC 1 or PKCS#8 format, and PKCS#8 keys may be encrypted in which
+// case passphrase is used to decrypt them
+func ParsePrivateKey(data []byte, passphrase string) (crypto.Signer, error) {
+	var err error
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded private key found")
+	}
+
+	var key any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "ENCRYPTED PRIVATE KEY":
+		if passphrase == "" {
+			return nil, errors.New("the private key is encrypted but no passphrase has been set")
+		}
+		key, err = pkcs8.ParsePKCS8PrivateKey(block.Bytes, []byte(passphrase))
+		if err != nil {
+			return nil, fmt.Errorf("could not decrypt the private key, check the passphrase: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %s", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("the private key can't be used to sign")
+	}
+	return signer, nil
+}
@@ -0,0 +1,183 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// OIDNonce identifies the nonce extension of requests and responses, see RFC
+// 8954
+var OIDNonce = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 2}
+
+// ParseResponse parses the response for cert and verifies its signature. It
+// differs from ocsp.ParseResponseForCert in that RSASSA-PSS signatures are
+// supported and the nonce of the response, if any, is returned. The response
+// must be signed by issuer or by an embedded certificate issued by it with the
+// OCSP signing extended key usage
+func ParseResponse(der []byte, cert, issuer *x509.Certificate) (*ocsp.Response, []byte, error) {
+	ret, basicResp, err := parseResponse(der, cert.SerialNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The response is signed by the issuer or by a delegated responder
+	responder := issuer
+	if ret.Certificate != nil && !bytes.Equal(ret.Certificate.Raw, issuer.Raw) {
+		if err := checkDelegatedResponder(ret.Certificate, issuer); err != nil {
+			return nil, nil, err
+		}
+		responder = ret.Certificate
+	}
+
+	if ret.SignatureAlgorithm == x509.UnknownSignatureAlgorithm {
+		return nil, nil, fmt.Errorf("unsupported signature algorithm %v", basicResp.SignatureAlgorithm.Algorithm)
+	}
+	if err := responder.CheckSignature(ret.SignatureAlgorithm, ret.TBSResponseData, ret.Signature); err != nil {
+		return nil, nil, fmt.Errorf("bad OCSP signature: %v", err)
+	}
+
+	for _, ext := range ret.Extensions {
+		if ext.Critical {
+			return nil, nil, errors.New("unsupported critical extension")
+		}
+	}
+
+	var nonce []byte
+	for _, ext := range basicResp.TBSResponseData.ResponseExtensions {
+		if ext.Id.Equal(OIDNonce) {
+			nonce = UnwrapNonce(ext.Value)
+		}
+	}
+
+	return ret, nonce, nil
+}
+
+// ReadResponse parses a response without verifying its signature and returns
+// its first single response. It's meant for responses signed by this
+// responder, such as the cached ones, and unlike ocsp.ParseResponse it accepts
+// RSASSA-PSS signatures
+func ReadResponse(der []byte) (*ocsp.Response, error) {
+	ret, _, err := parseResponse(der, nil)
+	return ret, err
+}
+
+// parseResponse decodes a basic response and the single response for serial,
+// or the first one if serial is nil. The signature isn't verified
+func parseResponse(der []byte, serial *big.Int) (*ocsp.Response, *basicResponse, error) {
+	var resp responseASN1
+	rest, err := asn1.Unmarshal(der, &resp)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rest) > 0 {
+		return nil, nil, errors.New("trailing data in OCSP response")
+	}
+
+	if status := ocsp.ResponseStatus(resp.Status); status != ocsp.Success {
+		return nil, nil, ocsp.ResponseError{Status: status}
+	}
+
+	if !resp.Response.ResponseType.Equal(idPKIXOCSPBasic) {
+		return nil, nil, errors.New("bad OCSP response type")
+	}
+
+	var basicResp basicResponse
+	rest, err = asn1.Unmarshal(resp.Response.Response, &basicResp)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rest) > 0 {
+		return nil, nil, errors.New("trailing data in OCSP response")
+	}
+
+	var singleResp *singleResponse
+	for i, r := range basicResp.TBSResponseData.Responses {
+		if r.CertID.SerialNumber != nil && (serial == nil || serial.Cmp(r.CertID.SerialNumber) == 0) {
+			singleResp = &basicResp.TBSResponseData.Responses[i]
+			break
+		}
+	}
+	if singleResp == nil {
+		return nil, nil, errors.New("no response matching the certificate")
+	}
+
+	ret := &ocsp.Response{
+		Raw:                der,
+		TBSResponseData:    basicResp.TBSResponseData.Raw,
+		Signature:          basicResp.Signature.RightAlign(),
+		SignatureAlgorithm: SignatureAlgorithmFromAI(basicResp.SignatureAlgorithm),
+		Extensions:         singleResp.SingleExtensions,
+		SerialNumber:       singleResp.CertID.SerialNumber,
+		ProducedAt:         basicResp.TBSResponseData.ProducedAt,
+		ThisUpdate:         singleResp.ThisUpdate,
+		NextUpdate:         singleResp.NextUpdate,
+		IssuerHash:         HashAlgorithmFromOID(singleResp.CertID.HashAlgorithm.Algorithm),
+	}
+	if ret.IssuerHash == 0 {
+		return nil, nil, errors.New("unsupported issuer hash algorithm")
+	}
+
+	rawResponderID := basicResp.TBSResponseData.RawResponderID
+	switch rawResponderID.Tag {
+	case 1:
+		ret.RawResponderName = rawResponderID.Bytes
+	case 2:
+		if rest, err := asn1.Unmarshal(rawResponderID.Bytes, &ret.ResponderKeyHash); err != nil || len(rest) != 0 {
+			return nil, nil, errors.New("invalid responder key hash")
+		}
+	default:
+		return nil, nil, errors.New("invalid responder id tag")
+	}
+
+	if len(basicResp.Certificates) > 0 {
+		ret.Certificate, err = x509.ParseCertificate(basicResp.Certificates[0].FullBytes)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	switch {
+	case bool(singleResp.Good):
+		ret.Status = ocsp.Good
+	case bool(singleResp.Unknown):
+		ret.Status = ocsp.Unknown
+	default:
+		ret.Status = ocsp.Revoked
+		ret.RevokedAt = singleResp.Revoked.RevocationTime
+		ret.RevocationReason = int(singleResp.Revoked.Reason)
+	}
+
+	return ret, &basicResp, nil
+}
+
+// checkDelegatedResponder verifies that the certificate embedded in the
+// response was issued by the CA to sign OCSP responses, see RFC 6960
+// section 4.2.2.2
+func checkDelegatedResponder(responder, issuer *x509.Certificate) error {
+	if err := responder.CheckSignatureFrom(issuer); err != nil {
+		return fmt.Errorf("the responder certificate is not issued by the CA: %v", err)
+	}
+
+	for _, usage := range responder.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageOCSPSigning {
+			return nil
+		}
+	}
+	return errors.New("the responder certificate doesn't have the OCSP signing extended key usage")
+}
+
+// UnwrapNonce returns the nonce of an extension value. RFC 8954 encodes it
+// as an OCTET STRING but some implementations send the raw bytes
+func UnwrapNonce(value []byte) []byte {
+	var nonce []byte
+	if rest, err := asn1.Unmarshal(value, &nonce); err == nil && len(rest) == 0 {
+		return nonce
+	}
+	return value
+}
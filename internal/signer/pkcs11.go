@@ -0,0 +1,48 @@
+//go:build cgo
+
+package signer
+
+import (
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"io"
+
+	"github.com/ThalesGroup/crypto11"
+)
+
+// NewPKCS11Signer opens the PKCS#11 module and finds the key pair labelled
+// KeyLabel. The returned closer must be called to release the token
+func NewPKCS11Signer(cfg PKCS11Config, cert *x509.Certificate) (crypto.Signer, io.Closer, error) {
+	p11Config := crypto11.Config{
+		Path:       cfg.Module,
+		TokenLabel: cfg.TokenLabel,
+		Pin:        cfg.PIN,
+	}
+	if cfg.TokenLabel == "" {
+		slot := cfg.Slot
+		p11Config.SlotNumber = &slot
+	}
+
+	ctx, err := crypto11.Configure(&p11Config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not open PKCS#11 module %s: %v", cfg.Module, err)
+	}
+
+	key, err := ctx.FindKeyPair(nil, []byte(cfg.KeyLabel))
+	if err != nil {
+		ctx.Close()
+		return nil, nil, fmt.Errorf("could not find PKCS#11 key %s: %v", cfg.KeyLabel, err)
+	}
+	if key == nil {
+		ctx.Close()
+		return nil, nil, fmt.Errorf("PKCS#11 key %s not found", cfg.KeyLabel)
+	}
+
+	if err := CheckPublicKey(key, cert); err != nil {
+		ctx.Close()
+		return nil, nil, err
+	}
+
+	return key, ctx, nil
+}
@@ -0,0 +1,16 @@
+//go:build !cgo
+
+package signer
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"io"
+)
+
+// NewPKCS11Signer is not available as PKCS#11 modules can only be loaded
+// when the responder is built with cgo
+func NewPKCS11Signer(cfg PKCS11Config, cert *x509.Certificate) (crypto.Signer, io.Closer, error) {
+	return nil, nil, errors.New("PKCS#11 support requires the responder to be built with cgo")
+}
@@ -0,0 +1,125 @@
+//go:build cgo
+
+package signer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const softHSMPIN = "1234"
+
+// softHSMModule returns the path of the SoftHSM module, read from
+// SOFTHSM2_MODULE or looked up in the usual locations. The test is skipped if
+// SoftHSM isn't configured or installed
+func softHSMModule(t *testing.T) string {
+	t.Helper()
+
+	if os.Getenv("SOFTHSM2_CONF") == "" {
+		t.Skip("SOFTHSM2_CONF is not set")
+	}
+	if _, err := exec.LookPath("softhsm2-util"); err != nil {
+		t.Skip("softhsm2-util is not installed")
+	}
+
+	candidates := []string{
+		os.Getenv("SOFTHSM2_MODULE"),
+		"/usr/lib/softhsm/libsofthsm2.so",
+		"/usr/lib/x86_64-linux-gnu/softhsm/libsofthsm2.so",
+		"/usr/lib/aarch64-linux-gnu/softhsm/libsofthsm2.so",
+		"/usr/lib64/pkcs11/libsofthsm2.so",
+		"/usr/local/lib/softhsm/libsofthsm2.so",
+		"/opt/homebrew/lib/softhsm/libsofthsm2.so",
+	}
+	for _, module := range candidates {
+		if module == "" {
+			continue
+		}
+		if _, err := os.Stat(module); err == nil {
+			return module
+		}
+	}
+
+	t.Skip("the SoftHSM module was not found, set SOFTHSM2_MODULE")
+	return ""
+}
+
+func softHSMUtil(t *testing.T, args ...string) {
+	t.Helper()
+
+	if out, err := exec.Command("softhsm2-util", args...).CombinedOutput(); err != nil {
+		t.Fatalf("softhsm2-util %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+// importKey initialises a new token and imports key into it with the label
+// ocsp, the token is deleted when the test ends
+func importKey(t *testing.T, key crypto.Signer) string {
+	t.Helper()
+
+	label := fmt.Sprintf("ocsp-test-%d", time.Now().UnixNano())
+	softHSMUtil(t, "--init-token", "--free", "--label", label, "--pin", softHSMPIN, "--so-pin", softHSMPIN)
+	t.Cleanup(func() {
+		_ = exec.Command("softhsm2-util", "--delete-token", "--token", label).Run()
+	})
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "ocsp.key")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	softHSMUtil(t, "--import", path, "--token", label, "--label", "ocsp", "--id", "01", "--pin", softHSMPIN)
+	return label
+}
+
+func TestPKCS11Signer(t *testing.T) {
+	module := softHSMModule(t)
+
+	tests := []struct {
+		name string
+		key  func() (crypto.Signer, error)
+	}{
+		{"RSA", func() (crypto.Signer, error) { return rsa.GenerateKey(rand.Reader, 2048) }},
+		{"ECDSA", func() (crypto.Signer, error) { return ecdsa.GenerateKey(elliptic.P256(), rand.Reader) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := tt.key()
+			if err != nil {
+				t.Fatal(err)
+			}
+			ca, responder := newResponder(t, key.Public())
+			label := importKey(t, key)
+
+			priv, closer, err := NewPKCS11Signer(PKCS11Config{
+				Module:     module,
+				TokenLabel: label,
+				KeyLabel:   "ocsp",
+				PIN:        softHSMPIN,
+			}, responder)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer closer.Close()
+
+			signAndParse(t, ca, responder, priv, ResponseOptions{})
+		})
+	}
+}
@@ -0,0 +1,447 @@
+package signer
+
+// CreateResponse and the ASN.1 structures below are adapted from
+// golang.org/x/crypto/ocsp, which always identifies the responder by name
+//
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// ResponderID selects how the responder identifies itself in the responses,
+// see RFC 6960 section 4.2.1
+type ResponderID int
+
+const (
+	// ByName uses the subject of the OCSP certificate
+	ByName ResponderID = iota
+	// ByKey uses the SHA-1 hash of the OCSP certificate public key
+	ByKey
+)
+
+func (r ResponderID) String() string {
+	if r == ByKey {
+		return "key"
+	}
+	return "name"
+}
+
+// ParseResponderID parses the responder ID type, name or key. An empty
+// string selects ByName
+func ParseResponderID(value string) (ResponderID, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "name", "byname":
+		return ByName, nil
+	case "key", "bykey":
+		return ByKey, nil
+	default:
+		return ByName, fmt.Errorf("unknown responder ID type %q, use name or key", value)
+	}
+}
+
+// ResponseOptions holds the settings used to sign responses
+type ResponseOptions struct {
+	ResponderID ResponderID
+	// SignatureAlgorithm is the algorithm used to sign responses, if unset
+	// it's chosen from the key type: SHA-256 with RSA or ECDSA with a hash
+	// matching the curve size
+	SignatureAlgorithm x509.SignatureAlgorithm
+}
+
+// Validate checks that the signature algorithm can be used with the public
+// key of the OCSP signer
+func (o ResponseOptions) Validate(pub crypto.PublicKey) error {
+	_, _, _, err := signingParamsForPublicKey(pub, o.SignatureAlgorithm)
+	return err
+}
+
+// ParseSignatureAlgorithm returns the signature algorithm matching a name
+// such as SHA256-RSA, SHA256-RSAPSS or ECDSA-SHA384. An empty string
+// selects the default algorithm for the key
+func ParseSignatureAlgorithm(name string) (x509.SignatureAlgorithm, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return x509.UnknownSignatureAlgorithm, nil
+	}
+
+	for _, details := range signatureAlgorithmDetails {
+		if strings.EqualFold(details.algo.String(), name) {
+			return details.algo, nil
+		}
+	}
+
+	names := []string{}
+	for _, details := range signatureAlgorithmDetails {
+		names = append(names, details.algo.String())
+	}
+	return x509.UnknownSignatureAlgorithm, fmt.Errorf("unsupported signature algorithm %q, use one of %s", name, strings.Join(names, ", "))
+}
+
+// HashAlgorithmFromOID returns the hash algorithm of a CertID, or zero if
+// it isn't supported
+func HashAlgorithmFromOID(oid asn1.ObjectIdentifier) crypto.Hash {
+	for hash, hashOID := range hashOIDs {
+		if oid.Equal(hashOID) {
+			return hash
+		}
+	}
+	return crypto.Hash(0)
+}
+
+// SignatureAlgorithmFromAI returns the signature algorithm of an algorithm
+// identifier, RSASSA-PSS is only recognized with the parameters produced by
+// crypto/x509, which are the ones used in practice
+func SignatureAlgorithmFromAI(ai pkix.AlgorithmIdentifier) x509.SignatureAlgorithm {
+	for _, details := range signatureAlgorithmDetails {
+		if !ai.Algorithm.Equal(details.oid) {
+			continue
+		}
+		if !details.isRSAPSS || bytes.Equal(ai.Parameters.FullBytes, details.params.FullBytes) {
+			return details.algo
+		}
+	}
+	return x509.UnknownSignatureAlgorithm
+}
+
+type certID struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	NameHash      []byte
+	IssuerKeyHash []byte
+	SerialNumber  *big.Int
+}
+
+type responseASN1 struct {
+	Status   asn1.Enumerated
+	Response responseBytes `asn1:"explicit,tag:0,optional"`
+}
+
+type responseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type basicResponse struct {
+	TBSResponseData    responseData
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+type responseData struct {
+	Raw                asn1.RawContent
+	Version            int `asn1:"optional,default:0,explicit,tag:0"`
+	RawResponderID     asn1.RawValue
+	ProducedAt         time.Time `asn1:"generalized"`
+	Responses          []singleResponse
+	ResponseExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type singleResponse struct {
+	CertID           certID
+	Good             asn1.Flag        `asn1:"tag:0,optional"`
+	Revoked          revokedInfo      `asn1:"tag:1,optional"`
+	Unknown          asn1.Flag        `asn1:"tag:2,optional"`
+	ThisUpdate       time.Time        `asn1:"generalized"`
+	NextUpdate       time.Time        `asn1:"generalized,explicit,tag:0,optional"`
+	SingleExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type revokedInfo struct {
+	RevocationTime time.Time       `asn1:"generalized"`
+	Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+}
+
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+var (
+	idPKIXOCSPBasic = asn1.ObjectIdentifier([]int{1, 3, 6, 1, 5, 5, 7, 48, 1, 1})
+
+	oidSignatureSHA1WithRSA     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 5}
+	oidSignatureSHA256WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidSignatureSHA384WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}
+	oidSignatureSHA512WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}
+	oidSignatureRSAPSS          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 10}
+	oidSignatureECDSAWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 1}
+	oidSignatureECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidSignatureECDSAWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidSignatureECDSAWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+)
+
+var hashOIDs = map[crypto.Hash]asn1.ObjectIdentifier{
+	crypto.SHA1:   asn1.ObjectIdentifier([]int{1, 3, 14, 3, 2, 26}),
+	crypto.SHA256: asn1.ObjectIdentifier([]int{2, 16, 840, 1, 101, 3, 4, 2, 1}),
+	crypto.SHA384: asn1.ObjectIdentifier([]int{2, 16, 840, 1, 101, 3, 4, 2, 2}),
+	crypto.SHA512: asn1.ObjectIdentifier([]int{2, 16, 840, 1, 101, 3, 4, 2, 3}),
+}
+
+// RSASSA-PSS parameters with MGF1 using the same hash and a salt length
+// equal to the hash size, as encoded by crypto/x509
+var (
+	nullRawValue        = asn1.RawValue{Tag: 5 /* ASN.1 NULL */}
+	emptyRawValue       = asn1.RawValue{}
+	pssParametersSHA256 = asn1.RawValue{FullBytes: []byte{48, 52, 160, 15, 48, 13, 6, 9, 96, 134, 72, 1, 101, 3, 4, 2, 1, 5, 0, 161, 28, 48, 26, 6, 9, 42, 134, 72, 134, 247, 13, 1, 1, 8, 48, 13, 6, 9, 96, 134, 72, 1, 101, 3, 4, 2, 1, 5, 0, 162, 3, 2, 1, 32}}
+	pssParametersSHA384 = asn1.RawValue{FullBytes: []byte{48, 52, 160, 15, 48, 13, 6, 9, 96, 134, 72, 1, 101, 3, 4, 2, 2, 5, 0, 161, 28, 48, 26, 6, 9, 42, 134, 72, 134, 247, 13, 1, 1, 8, 48, 13, 6, 9, 96, 134, 72, 1, 101, 3, 4, 2, 2, 5, 0, 162, 3, 2, 1, 48}}
+	pssParametersSHA512 = asn1.RawValue{FullBytes: []byte{48, 52, 160, 15, 48, 13, 6, 9, 96, 134, 72, 1, 101, 3, 4, 2, 3, 5, 0, 161, 28, 48, 26, 6, 9, 42, 134, 72, 134, 247, 13, 1, 1, 8, 48, 13, 6, 9, 96, 134, 72, 1, 101, 3, 4, 2, 3, 5, 0, 162, 3, 2, 1, 64}}
+)
+
+var signatureAlgorithmDetails = []struct {
+	algo       x509.SignatureAlgorithm
+	oid        asn1.ObjectIdentifier
+	params     asn1.RawValue
+	pubKeyAlgo x509.PublicKeyAlgorithm
+	hash       crypto.Hash
+	isRSAPSS   bool
+}{
+	{x509.SHA1WithRSA, oidSignatureSHA1WithRSA, nullRawValue, x509.RSA, crypto.SHA1, false},
+	{x509.SHA256WithRSA, oidSignatureSHA256WithRSA, nullRawValue, x509.RSA, crypto.SHA256, false},
+	{x509.SHA384WithRSA, oidSignatureSHA384WithRSA, nullRawValue, x509.RSA, crypto.SHA384, false},
+	{x509.SHA512WithRSA, oidSignatureSHA512WithRSA, nullRawValue, x509.RSA, crypto.SHA512, false},
+	{x509.SHA256WithRSAPSS, oidSignatureRSAPSS, pssParametersSHA256, x509.RSA, crypto.SHA256, true},
+	{x509.SHA384WithRSAPSS, oidSignatureRSAPSS, pssParametersSHA384, x509.RSA, crypto.SHA384, true},
+	{x509.SHA512WithRSAPSS, oidSignatureRSAPSS, pssParametersSHA512, x509.RSA, crypto.SHA512, true},
+	{x509.ECDSAWithSHA1, oidSignatureECDSAWithSHA1, emptyRawValue, x509.ECDSA, crypto.SHA1, false},
+	{x509.ECDSAWithSHA256, oidSignatureECDSAWithSHA256, emptyRawValue, x509.ECDSA, crypto.SHA256, false},
+	{x509.ECDSAWithSHA384, oidSignatureECDSAWithSHA384, emptyRawValue, x509.ECDSA, crypto.SHA384, false},
+	{x509.ECDSAWithSHA512, oidSignatureECDSAWithSHA512, emptyRawValue, x509.ECDSA, crypto.SHA512, false},
+}
+
+// CreateResponse returns a DER-encoded OCSP response with the specified
+// contents, signed by priv and identified as set in opts. It behaves like
+// ocsp.CreateResponse otherwise
+func CreateResponse(issuer, responderCert *x509.Certificate, template ocsp.Response, priv crypto.Signer, opts ResponseOptions) ([]byte, error) {
+	var publicKeyInfo subjectPublicKeyInfo
+	if _, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &publicKeyInfo); err != nil {
+		return nil, err
+	}
+
+	if template.IssuerHash == 0 {
+		template.IssuerHash = crypto.SHA1
+	}
+	hashOID, ok := hashOIDs[template.IssuerHash]
+	if !ok {
+		return nil, errors.New("unsupported issuer hash algorithm")
+	}
+
+	if !template.IssuerHash.Available() {
+		return nil, fmt.Errorf("issuer hash algorithm %v not linked into binary", template.IssuerHash)
+	}
+	h := template.IssuerHash.New()
+	h.Write(publicKeyInfo.PublicKey.RightAlign())
+	issuerKeyHash := h.Sum(nil)
+
+	h.Reset()
+	h.Write(issuer.RawSubject)
+	issuerNameHash := h.Sum(nil)
+
+	innerResponse := singleResponse{
+		CertID: certID{
+			HashAlgorithm: pkix.AlgorithmIdentifier{
+				Algorithm:  hashOID,
+				Parameters: asn1.RawValue{Tag: 5 /* ASN.1 NULL */},
+			},
+			NameHash:      issuerNameHash,
+			IssuerKeyHash: issuerKeyHash,
+			SerialNumber:  template.SerialNumber,
+		},
+		ThisUpdate:       template.ThisUpdate.UTC(),
+		NextUpdate:       template.NextUpdate.UTC(),
+		SingleExtensions: template.ExtraExtensions,
+	}
+
+	switch template.Status {
+	case ocsp.Good:
+		innerResponse.Good = true
+	case ocsp.Unknown:
+		innerResponse.Unknown = true
+	case ocsp.Revoked:
+		innerResponse.Revoked = revokedInfo{
+			RevocationTime: template.RevokedAt.UTC(),
+			Reason:         asn1.Enumerated(template.RevocationReason),
+		}
+	}
+
+	rawResponderID, err := responderID(responderCert, opts.ResponderID)
+	if err != nil {
+		return nil, err
+	}
+	tbsResponseData := responseData{
+		Version:        0,
+		RawResponderID: rawResponderID,
+		ProducedAt:     time.Now().Truncate(time.Minute).UTC(),
+		Responses:      []singleResponse{innerResponse},
+	}
+
+	tbsResponseDataDER, err := asn1.Marshal(tbsResponseData)
+	if err != nil {
+		return nil, err
+	}
+
+	if template.SignatureAlgorithm == x509.UnknownSignatureAlgorithm {
+		template.SignatureAlgorithm = opts.SignatureAlgorithm
+	}
+	hashFunc, signerOpts, signatureAlgorithm, err := signingParamsForPublicKey(priv.Public(), template.SignatureAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	responseHash := hashFunc.New()
+	responseHash.Write(tbsResponseDataDER)
+	signature, err := priv.Sign(rand.Reader, responseHash.Sum(nil), signerOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	response := basicResponse{
+		TBSResponseData:    tbsResponseData,
+		SignatureAlgorithm: signatureAlgorithm,
+		Signature: asn1.BitString{
+			Bytes:     signature,
+			BitLength: 8 * len(signature),
+		},
+	}
+	if template.Certificate != nil {
+		response.Certificates = []asn1.RawValue{
+			{FullBytes: template.Certificate.Raw},
+		}
+	}
+	responseDER, err := asn1.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(responseASN1{
+		Status: asn1.Enumerated(ocsp.Success),
+		Response: responseBytes{
+			ResponseType: idPKIXOCSPBasic,
+			Response:     responseDER,
+		},
+	})
+}
+
+// responderID encodes the ResponderID choice, byName is [1] Name and byKey
+// is [2] KeyHash, the SHA-1 hash of the responder's public key excluding the
+// tag, length and number of unused bits
+func responderID(responderCert *x509.Certificate, id ResponderID) (asn1.RawValue, error) {
+	if responderCert == nil {
+		return asn1.RawValue{}, errors.New("the OCSP certificate is required to sign responses")
+	}
+
+	if id != ByKey {
+		return asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        1,
+			IsCompound: true,
+			Bytes:      responderCert.RawSubject,
+		}, nil
+	}
+
+	var publicKeyInfo subjectPublicKeyInfo
+	if _, err := asn1.Unmarshal(responderCert.RawSubjectPublicKeyInfo, &publicKeyInfo); err != nil {
+		return asn1.RawValue{}, err
+	}
+	keyHash := sha1.Sum(publicKeyInfo.PublicKey.RightAlign())
+
+	encoded, err := asn1.Marshal(keyHash[:])
+	if err != nil {
+		return asn1.RawValue{}, err
+	}
+
+	return asn1.RawValue{
+		Class:      asn1.ClassContextSpecific,
+		Tag:        2,
+		IsCompound: true,
+		Bytes:      encoded,
+	}, nil
+}
+
+// signingParamsForPublicKey returns the hash, the options passed to the
+// signer and the algorithm identifier for the requested signature algorithm,
+// or for the default one of the key type if none is requested
+func signingParamsForPublicKey(pub crypto.PublicKey, requestedSigAlgo x509.SignatureAlgorithm) (hashFunc crypto.Hash, signerOpts crypto.SignerOpts, sigAlgo pkix.AlgorithmIdentifier, err error) {
+	var pubType x509.PublicKeyAlgorithm
+
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		pubType = x509.RSA
+		hashFunc = crypto.SHA256
+		sigAlgo.Algorithm = oidSignatureSHA256WithRSA
+		sigAlgo.Parameters = nullRawValue
+
+	case *ecdsa.PublicKey:
+		pubType = x509.ECDSA
+
+		switch pub.Curve {
+		case elliptic.P224(), elliptic.P256():
+			hashFunc = crypto.SHA256
+			sigAlgo.Algorithm = oidSignatureECDSAWithSHA256
+		case elliptic.P384():
+			hashFunc = crypto.SHA384
+			sigAlgo.Algorithm = oidSignatureECDSAWithSHA384
+		case elliptic.P521():
+			hashFunc = crypto.SHA512
+			sigAlgo.Algorithm = oidSignatureECDSAWithSHA512
+		default:
+			err = errors.New("x509: unknown elliptic curve")
+		}
+
+	default:
+		err = errors.New("x509: only RSA and ECDSA keys supported")
+	}
+
+	if err != nil {
+		return
+	}
+
+	signerOpts = hashFunc
+	if requestedSigAlgo == x509.UnknownSignatureAlgorithm {
+		return
+	}
+
+	found := false
+	for _, details := range signatureAlgorithmDetails {
+		if details.algo == requestedSigAlgo {
+			if details.pubKeyAlgo != pubType {
+				err = errors.New("x509: requested SignatureAlgorithm does not match private key type")
+				return
+			}
+			sigAlgo.Algorithm, sigAlgo.Parameters, hashFunc = details.oid, details.params, details.hash
+			signerOpts = hashFunc
+			if details.isRSAPSS {
+				signerOpts = &rsa.PSSOptions{
+					SaltLength: rsa.PSSSaltLengthEqualsHash,
+					Hash:       hashFunc,
+				}
+			}
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		err = errors.New("x509: unknown SignatureAlgorithm")
+	}
+
+	return
+}
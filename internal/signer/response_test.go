@@ -0,0 +1,51 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"testing"
+)
+
+func TestResponderID(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, responder := newResponder(t, key.Public())
+
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(responder.RawSubjectPublicKeyInfo, &spki); err != nil {
+		t.Fatal(err)
+	}
+	keyHash := sha1.Sum(spki.PublicKey.RightAlign())
+
+	tests := []struct {
+		id      ResponderID
+		name    []byte
+		keyHash []byte
+	}{
+		{ByName, responder.RawSubject, nil},
+		{ByKey, nil, keyHash[:]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id.String(), func(t *testing.T) {
+			response := signAndParse(t, ca, responder, key, ResponseOptions{ResponderID: tt.id})
+
+			if !bytes.Equal(response.RawResponderName, tt.name) {
+				t.Errorf("RawResponderName is %x, expected %x", response.RawResponderName, tt.name)
+			}
+			if !bytes.Equal(response.ResponderKeyHash, tt.keyHash) {
+				t.Errorf("ResponderKeyHash is %x, expected %x", response.ResponderKeyHash, tt.keyHash)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// PKCS11Config holds the settings used to find the OCSP signing key in a
+// PKCS#11 token or HSM
+type PKCS11Config struct {
+	Module     string
+	Slot       int
+	TokenLabel string
+	KeyLabel   string
+	PIN        string
+}
+
+// Enabled reports if the signing key must be read from a PKCS#11 token
+// instead of from a PEM file
+func (c PKCS11Config) Enabled() bool {
+	return c.Module != ""
+}
+
+// ReadSecret returns a secret such as a PIN or a passphrase. It's taken from
+// value if set, then from the content of file and finally from the systemd
+// credential with the given name, read from $CREDENTIALS_DIRECTORY
+func ReadSecret(value string, file string, credential string) (string, error) {
+	if value != "" {
+		return value, nil
+	}
+
+	if file == "" && credential != "" {
+		dir := os.Getenv("CREDENTIALS_DIRECTORY")
+		if dir == "" {
+			return "", fmt.Errorf("could not read systemd credential %s, CREDENTIALS_DIRECTORY is not set", credential)
+		}
+		file = filepath.Join(dir, credential)
+	}
+
+	if file == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("could not read secret from %s: %v", file, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// CheckPublicKey verifies that the key belongs to the OCSP certificate
+func CheckPublicKey(s crypto.Signer, cert *x509.Certificate) error {
+	if cert == nil {
+		return nil
+	}
+
+	type publicKey interface {
+		Equal(crypto.PublicKey) bool
+	}
+
+	pub, ok := s.Public().(publicKey)
+	if !ok {
+		return fmt.Errorf("unsupported public key type %s", reflect.TypeOf(s.Public()))
+	}
+	if !pub.Equal(cert.PublicKey) {
+		return fmt.Errorf("the private key doesn't match the OCSP certificate")
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// newResponder returns a CA and an OCSP signing certificate issued by it for
+// the public key pub
+func newResponder(t *testing.T, pub crypto.PublicKey) (*x509.Certificate, *x509.Certificate) {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "OpenUEM Test CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responderTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "OpenUEM Test OCSP Responder"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+	}
+	responderDER, err := x509.CreateCertificate(rand.Reader, responderTemplate, ca, pub, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	responder, err := x509.ParseCertificate(responderDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ca, responder
+}
+
+// signAndParse signs a good response for serial 4242 with priv and parses it
+// with golang.org/x/crypto/ocsp, which verifies the signature
+func signAndParse(t *testing.T, ca, responder *x509.Certificate, priv crypto.Signer, opts ResponseOptions) *ocsp.Response {
+	t.Helper()
+
+	now := time.Now()
+	template := ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: big.NewInt(4242),
+		ThisUpdate:   now,
+		NextUpdate:   now.Add(time.Hour),
+		Certificate:  responder,
+	}
+
+	der, err := CreateResponse(ca, responder, template, priv, opts)
+	if err != nil {
+		t.Fatalf("could not sign the response: %v", err)
+	}
+
+	response, err := ocsp.ParseResponse(der, ca)
+	if err != nil {
+		t.Fatalf("could not parse the response: %v", err)
+	}
+	if response.Status != ocsp.Good || response.SerialNumber.Cmp(template.SerialNumber) != 0 {
+		t.Fatalf("unexpected response: status %d, serial %s", response.Status, response.SerialNumber)
+	}
+
+	return response
+}
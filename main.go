@@ -26,5 +26,11 @@ func getCommands() []*cli.Command {
 	return []*cli.Command{
 		commands.StartOCSPResponder(),
 		commands.StopOCSPResponder(),
+		commands.StatusOCSPResponder(),
+		commands.ReloadOCSPResponder(),
+		commands.ConfigOCSPResponder(),
+		commands.MigrateOCSPResponder(),
+		commands.PregenerateOCSPResponses(),
+		commands.CheckCertificate(),
 	}
 }